@@ -7,8 +7,10 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/pokemium/gsnes/core"
-	"github.com/pokemium/iro"
+	"github.com/pokemium/gsnes/filter"
+	"github.com/pokemium/gsnes/gdb"
 )
 
 // pauseボタンがリリースされた場合にtrueに、押された場合にfalseにする
@@ -47,12 +49,21 @@ var btnMap = map[ebiten.GamepadButton]string{
 }
 
 type emulator struct {
-	sfc         core.SuperFamicom
-	frameBuffer []iro.RGB555
-	frame       uint64
-	debug       bool
-	win         window
-	texts       []*text
+	sfc      core.SuperFamicom
+	raw      *image.RGBA   // frame written by core (large enough for any resolution)
+	screen   *ebiten.Image // game screen texture, filtered frame is uploaded into it every frame
+	fbw, fbh int           // frame size (before filter)
+	frame    uint64
+	debug    bool
+	win      window
+	texts    []*text
+	tiles    tileViewer
+	palette  paletteViewer
+	gdb      *gdb.Server // nil if GDB stub is disabled
+
+	filter     filter.Chain
+	filterName string
+	preset     int // index of filter.Presets
 
 	// all queue tasks are executed on each Update()
 	queue queue
@@ -77,11 +88,13 @@ func new() *emulator {
 	sfc := core.New()
 	w, h := sfc.Resolution()
 	return &emulator{
-		sfc:         sfc,
-		frameBuffer: make([]iro.RGB555, w*h),
-		texts:       make([]*text, 0),
-		queue:       make([]*command, 0),
-		win:         window{"gsnes", color.RGBA{35, 27, 167, 255}},
+		sfc:   sfc,
+		raw:   &image.RGBA{Pix: make([]uint8, (core.HORIZONTAL*2)*(core.OVERSCAN*2)*4)},
+		fbw:   w,
+		fbh:   h,
+		texts: make([]*text, 0),
+		queue: make([]*command, 0),
+		win:   window{"gsnes", color.RGBA{35, 27, 167, 255}},
 	}
 }
 
@@ -96,19 +109,38 @@ func (e *emulator) setDebugMode(mode bool) {
 			e.sfc.MMap("VRAM", e.MMap(MMAP_VRAM, int(core.VRAM_SIZE)))
 			e.sfc.MMap("PALETTE", e.MMap(MMAP_PALETTE, int(core.PAL_SIZE)))
 		} else {
-			w, h := e.sfc.Resolution()
-			ebiten.SetWindowSize(w*2, h*2)
+			ebiten.SetWindowSize(core.HORIZONTAL*2, core.VERTICAL*2)
 		}
 	}))
 }
 
+// Load ROM and run at the frame rate of its region (NTSC: 60fps, PAL: 50fps)
+func (e *emulator) loadROM(romData []uint8) error {
+	if err := e.sfc.LoadROM(romData); err != nil {
+		return err
+	}
+
+	tps := 60
+	if e.sfc.Region() == core.REGION_PAL {
+		tps = 50
+	}
+	ebiten.SetMaxTPS(tps)
+	return nil
+}
+
 func (e *emulator) Update() error {
 	ebiten.SetWindowTitle(e.win.title)
 	e.queue.exec()
 	e.pollInput()
+	if e.gdb != nil {
+		e.gdb.Update()
+	}
 
 	if !e.sfc.Paused() {
 		e.sfc.RunFrame()
+		if b, ok := e.sfc.BreakReason(); ok {
+			fmt.Println("Break: " + b.String())
+		}
 	}
 
 	if e.debug {
@@ -128,9 +160,20 @@ func (e *emulator) Update() error {
 		}
 		e.debugPrint("Stack", s).Pos(512, 4)
 
+		calls := "Calls:\n"
+		for i, f := range e.sfc.CallStack() {
+			if i == 4 {
+				break
+			}
+			calls += fmt.Sprintf("%s %02X:%04X\n", f.Kind, f.Entry>>16, uint16(f.Entry))
+		}
+		e.debugPrint("Calls", calls).Pos(512, 150)
+
 		e.debugPrint("Status/Events", e.sfc.Status("EVENTS")).Pos(4, 250)
 		e.debugPrint("Status/SCREEN", e.sfc.Status("SCREEN")).Pos(4, 280)
 		e.debugPrint("Status/OAM", e.sfc.Status("OAM")).Pos(264, 250)
+		e.debugPrint("Tiles", e.tiles.update(e.sfc)).Pos(560, 232)
+		e.debugPrint("Palette", e.palette.update(e.sfc)).Pos(560, 392)
 	}
 	return nil
 }
@@ -138,11 +181,49 @@ func (e *emulator) Update() error {
 func (e *emulator) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
 	w, h := outsideWidth, outsideHeight
 	if !e.debug {
-		w, h = e.sfc.Resolution()
+		w, h = int(core.HORIZONTAL*2*e.aspect()+0.5), e.lines()*2
 	}
 	return w, h
 }
 
+// Pixel aspect ratio of the screen (8:7 with aspect filter, debug screen is always 1:1)
+func (e *emulator) aspect() float64 {
+	if e.debug {
+		return 1
+	}
+	return e.filter.PixelAspect()
+}
+
+func (e *emulator) setFilter(spec string) error {
+	c, err := filter.New(spec)
+	if err != nil {
+		return err
+	}
+	e.filter, e.filterName = c, spec
+	return nil
+}
+
+// Switch to next filter preset (Meta+F)
+func (e *emulator) nextFilter() {
+	e.preset = (e.preset + 1) % len(filter.Presets)
+	if filter.Presets[e.preset] == e.filterName {
+		e.preset = (e.preset + 1) % len(filter.Presets)
+	}
+	if err := e.setFilter(filter.Presets[e.preset]); err != nil {
+		fmt.Println(err) // keep the current chain
+		return
+	}
+	fmt.Println("Filter: " + e.filterName)
+}
+
+// lines returns the number of visible scanlines (224 or 239) regardless of interlace
+func (e *emulator) lines() int {
+	if e.fbh > core.OVERSCAN {
+		return e.fbh / 2
+	}
+	return e.fbh
+}
+
 func (e *emulator) Draw(screen *ebiten.Image) {
 	screen.Fill(e.win.backgroundColor)
 	for i := range e.texts {
@@ -150,20 +231,58 @@ func (e *emulator) Draw(screen *ebiten.Image) {
 	}
 
 	img := e.draw()
-	// writeGrid(img, 8)
-	screen.DrawImage(ebiten.NewImageFromImage(img), nil)
+
+	// Frame size changes in hires and interlace mode and by filters, so scale it into 256x224(or 239)(x2 if not debug mode)
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	scale := 2.0
+	if e.debug {
+		scale = 1.0
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale*core.HORIZONTAL*e.aspect()/float64(w), scale*float64(e.lines())/float64(h))
+	screen.DrawImage(img, op)
+
+	if e.debug && e.tiles.tex != nil {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(560, 250)
+		screen.DrawImage(e.tiles.tex, op)
+	}
+	if e.debug && e.palette.tex != nil {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(560, 410)
+		screen.DrawImage(e.palette.tex, op)
+	}
 	e.frame++
 }
 
-func (e *emulator) draw() *image.RGBA {
-	w, h := e.sfc.Resolution()
-	if e.sfc.Paused() {
-		return iro.RGB555ToImage(e.frameBuffer, w, h, nil)
+// Upload the filtered frame into screen texture (the last frame is kept while paused)
+func (e *emulator) draw() *ebiten.Image {
+	if e.screen != nil && e.sfc.Paused() {
+		return e.screen
 	}
 
-	screen := e.sfc.FrameBuffer()
-	copy(e.frameBuffer, screen)
-	return iro.RGB555ToImage(e.frameBuffer, w, h, nil)
+	raw, err := filter.Frame(e.sfc, e.raw)
+	if err != nil {
+		// keep the last frame
+		fmt.Println(err)
+		if e.screen == nil {
+			e.screen = ebiten.NewImage(core.HORIZONTAL, core.VERTICAL)
+		}
+		return e.screen
+	}
+	e.raw, e.fbw, e.fbh = raw, raw.Rect.Dx(), raw.Rect.Dy()
+	// writeGrid(raw, 8)
+
+	img := e.filter.Apply(raw)
+	if e.screen == nil || !e.screen.Bounds().Eq(img.Rect) {
+		// texture is recreated only when output size changes (hires, interlace, overscan, filter)
+		if e.screen != nil {
+			e.screen.Dispose()
+		}
+		e.screen = ebiten.NewImage(img.Rect.Dx(), img.Rect.Dy())
+	}
+	e.screen.ReplacePixels(img.Pix)
+	return e.screen
 }
 
 func (e *emulator) pollInput() {
@@ -193,6 +312,26 @@ func (e *emulator) pollInput() {
 	} else {
 		stepEnable = true
 	}
+
+	// Step over, step out (while paused)
+	if ebiten.IsKeyPressed(ebiten.KeyMeta) && inpututil.IsKeyJustPressed(ebiten.KeyO) && e.sfc.Paused() {
+		e.sfc.StepOver()
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyMeta) && inpututil.IsKeyJustPressed(ebiten.KeyU) && e.sfc.Paused() {
+		if err := e.sfc.StepOut(); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	// Switch video filter
+	if ebiten.IsKeyPressed(ebiten.KeyMeta) && inpututil.IsKeyJustPressed(ebiten.KeyF) {
+		e.queue = append(e.queue, newCommand(e.nextFilter))
+	}
+
+	// Dump BG maps
+	if ebiten.IsKeyPressed(ebiten.KeyMeta) && inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		e.queue = append(e.queue, newCommand(e.dumpBGMaps))
+	}
 }
 
 func (e *emulator) debugPrint(id, content string) *text {
@@ -2,10 +2,16 @@ package main
 
 import (
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
 	"log"
 	"os"
 
 	"github.com/edsrzf/mmap-go"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/pokemium/gsnes/core"
 )
 
 func (e *emulator) MMap(path string, size int) []uint8 {
@@ -34,3 +40,136 @@ func createEmptyFile(path string, size int) *os.File {
 	f.Write(buf)
 	return f
 }
+
+var tileFormats = [4]string{core.TILE_2BPP, core.TILE_4BPP, core.TILE_8BPP, core.TILE_MODE7}
+
+// VRAM tile viewer on debug screen
+//
+//	Meta+T: next format, Meta+N: next page, Meta+C: next palette
+type tileViewer struct {
+	format  int // index of tileFormats
+	page    int // 256 tiles per page
+	palette int
+	tex     *ebiten.Image
+	key     [5]uint64 // format, page, palette and VRAM/CGRAM write counters when tex is drawn
+	label   string
+}
+
+func (t *tileViewer) update(sfc core.SuperFamicom) string {
+	meta := ebiten.IsKeyPressed(ebiten.KeyMeta)
+	format := tileFormats[t.format]
+
+	pageSize := 256 * 8 * 8 * 8 // 8bpp
+	colors := 256
+	switch format {
+	case core.TILE_2BPP:
+		pageSize, colors = 256*8*8*2/8, 4
+	case core.TILE_4BPP:
+		pageSize, colors = 256*8*8*4/8, 16
+	case core.TILE_MODE7:
+		pageSize = 256 * 128 // all 256 tiles
+	}
+	pages := int(core.VRAM_SIZE) / pageSize
+	if format == core.TILE_MODE7 {
+		pages = 1
+	}
+
+	switch {
+	case meta && inpututil.IsKeyJustPressed(ebiten.KeyT):
+		t.format, t.page, t.palette = (t.format+1)%len(tileFormats), 0, 0
+	case meta && inpututil.IsKeyJustPressed(ebiten.KeyN):
+		t.page = (t.page + 1) % pages
+	case meta && inpututil.IsKeyJustPressed(ebiten.KeyC):
+		t.palette = (t.palette + 1) % (256 / colors)
+	}
+
+	vram, cgram := sfc.VideoWrites()
+	key := [5]uint64{uint64(t.format), uint64(t.page), uint64(t.palette), vram, cgram}
+	if t.tex != nil && key == t.key {
+		return t.label
+	}
+
+	img, err := sfc.Tiles(tileFormats[t.format], t.page*pageSize, pageSize, t.palette)
+	if err != nil {
+		return err.Error()
+	}
+	t.tex = replaceTexture(t.tex, img)
+	t.key = key
+	t.label = fmt.Sprintf("VRAM %s 0x%04X Pal:%d", tileFormats[t.format], t.page*pageSize, t.palette)
+	return t.label
+}
+
+// Copy img into tex, tex is allocated again only when the size changes
+func replaceTexture(tex *ebiten.Image, img image.Image) *ebiten.Image {
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Rect, img, rgba.Rect.Min, draw.Src)
+	}
+
+	w, h := rgba.Rect.Dx(), rgba.Rect.Dy()
+	if tex == nil || tex.Bounds().Dx() != w || tex.Bounds().Dy() != h {
+		if tex != nil {
+			tex.Dispose()
+		}
+		tex = ebiten.NewImage(w, h)
+	}
+	tex.ReplacePixels(rgba.Pix)
+	return tex
+}
+
+// Dump whole tilemaps of BG1..BG4 into PNG files (Meta+B)
+func (e *emulator) dumpBGMaps() {
+	for i := 1; i <= 4; i++ {
+		img, err := e.sfc.BGMap(i)
+		if err != nil {
+			continue // disabled in current mode
+		}
+
+		path := fmt.Sprintf("bg%d.png", i)
+		if err := writePNG(path, img); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		fmt.Println("Dump ", path)
+	}
+}
+
+// CGRAM palette panel on debug screen (BG palettes on upper half, OBJ palettes on lower half)
+type paletteViewer struct {
+	img   *image.RGBA
+	tex   *ebiten.Image
+	cgram uint64 // CGRAM write counter when tex is drawn
+}
+
+const swatch = 8 // px per color
+
+func (p *paletteViewer) update(sfc core.SuperFamicom) string {
+	_, cgram := sfc.VideoWrites()
+	if p.tex != nil && cgram == p.cgram {
+		return "CGRAM BG/OBJ"
+	}
+	if p.img == nil {
+		p.img = image.NewRGBA(image.Rect(0, 0, 16*swatch, 16*swatch))
+	}
+
+	p.cgram = cgram
+	pal := sfc.Palette()
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 16; j++ {
+			p.fill(j, i, pal.BG[i][j])
+			p.fill(j, i+8, pal.OBJ[i][j])
+		}
+	}
+	p.tex = replaceTexture(p.tex, p.img)
+	return "CGRAM BG/OBJ"
+}
+
+func (p *paletteViewer) fill(col, row int, c core.Color) {
+	rgba := color.RGBA{c.R, c.G, c.B, 0xFF}
+	for y := 0; y < swatch; y++ {
+		for x := 0; x < swatch; x++ {
+			p.img.SetRGBA(col*swatch+x, row*swatch+y, rgba)
+		}
+	}
+}
@@ -35,7 +35,7 @@ func (q *queue) exec() {
 }
 
 // WritePNG writes image to png format
-func writePNG(dstPath string, i *image.RGBA) error {
+func writePNG(dstPath string, i image.Image) error {
 	if i == nil {
 		return errors.New("image is nil")
 	}
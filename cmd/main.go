@@ -4,9 +4,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/pokemium/gsnes/core"
+	"github.com/pokemium/gsnes/core/disasm"
+	"github.com/pokemium/gsnes/gdb"
 )
 
 var exits = []func(){}
@@ -53,6 +57,17 @@ func run() exitCode {
 		showVersion = flag.Bool("v", false, "show version")
 		showRomInfo = flag.Bool("r", false, "show rom info")
 		isDebug     = flag.Bool("d", false, "debug mode")
+		noSprLimit  = flag.Bool("nosprlimit", false, "disable sprite limits per line (removes flicker)")
+		dotRenderer = flag.Bool("dot", false, "use dot-based renderer for mid-scanline raster effects")
+		region      = flag.String("region", "auto", "video region (auto, ntsc, pal)")
+		videoFilter = flag.String("filter", "none", "video filter chain (e.g. hq2x, ntsc, crt, aspect,scale2x)")
+		disasmAddr  = flag.String("disasm", "", "disassemble rom from the address (e.g. 00:8000, reset) and exit")
+		disasmCount = flag.Int("count", 32, "number of instructions for -disasm")
+		tracePath   = flag.String("trace", "", "write instruction trace log into the file")
+		traceFormat = flag.String("traceformat", core.TRACE_BSNES, "trace log format (bsnes, mesen)")
+		traceRange  = flag.String("tracerange", "", "log only PC in the ranges (e.g. 00:8000-00:FFFF,7E:0000-7F:FFFF)")
+		gdbAddr     = flag.String("gdb", "", "start GDB remote protocol stub on the TCP address (e.g. localhost:2345)")
+		breakpoints = flag.String("break", "", "breakpoints separated by ';' (e.g. \"exec 00:8000; write 00:2118-00:2119 if A == $1F\")")
 	)
 
 	flag.Parse()
@@ -79,9 +94,59 @@ func run() exitCode {
 		return ExitCodeOK
 	}
 
+	if *disasmAddr != "" {
+		if err := printDisasm(romData, *disasmAddr, *disasmCount); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitCodeError
+		}
+		return ExitCodeOK
+	}
+
 	e := new()
 	e.setDebugMode(*isDebug)
-	e.sfc.LoadROM(romData)
+	e.sfc.SpriteLimit(!*noSprLimit)
+	e.sfc.DotRenderer(*dotRenderer)
+	if err := e.sfc.SetRegion(*region); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitCodeError
+	}
+	if err := e.setFilter(*videoFilter); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitCodeError
+	}
+	if err := e.loadROM(romData); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitCodeError
+	}
+
+	if *tracePath != "" {
+		ranges, err := parseRanges(*traceRange)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitCodeError
+		}
+		if err := e.sfc.StartTrace(*tracePath, *traceFormat, ranges...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitCodeError
+		}
+		exits = append(exits, func() { e.sfc.StopTrace() })
+	}
+
+	if err := addBreakpoints(e.sfc, *breakpoints); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitCodeError
+	}
+
+	if *gdbAddr != "" {
+		srv, err := gdb.Listen(*gdbAddr, e.sfc)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitCodeError
+		}
+		fmt.Println("GDB stub is listening on " + srv.Addr().String())
+		e.gdb = srv
+		exits = append(exits, func() { srv.Close() })
+	}
 
 	ebiten.SetWindowTitle("gsnes")
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
@@ -100,6 +165,96 @@ func run() exitCode {
 	return ExitCodeOK
 }
 
+// Disassemble rom image without running it (CPU starts in emulation mode)
+func printDisasm(romData []byte, addr string, n int) error {
+	mem, err := disasm.ROM(romData)
+	if err != nil {
+		return err
+	}
+
+	start := disasm.Vector(mem, 0xFFFC)
+	if addr != "reset" {
+		start, err = parseAddr(addr)
+		if err != nil {
+			return err
+		}
+	}
+
+	state := disasm.State{P: 0x34, E: true}
+	for _, inst := range disasm.Disassemble(mem, start, n, state) {
+		bytes := ""
+		for _, b := range inst.Bytes {
+			bytes += fmt.Sprintf("%02X ", b)
+		}
+		fmt.Printf("%02X:%04X  %-12s %s\n", inst.Addr>>16, uint16(inst.Addr), bytes, inst)
+	}
+	return nil
+}
+
+// "00:8000" or "008000"
+func parseAddr(s string) (uint32, error) {
+	val, err := strconv.ParseUint(strings.ReplaceAll(strings.TrimSpace(s), ":", ""), 16, 24)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address: %s", s)
+	}
+	return uint32(val), nil
+}
+
+// "00:8000-00:FFFF,7E:0000-7F:FFFF"
+func parseRanges(s string) ([]core.AddrRange, error) {
+	ranges := []core.AddrRange{}
+	if s == "" {
+		return ranges, nil
+	}
+
+	for _, r := range strings.Split(s, ",") {
+		p := strings.Split(r, "-")
+		if len(p) != 2 {
+			return nil, fmt.Errorf("invalid address range: %s", r)
+		}
+		start, err := parseAddr(p[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseAddr(p[1])
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, core.AddrRange{Start: start, End: end})
+	}
+	return ranges, nil
+}
+
+// "exec 00:8000; write 7E:0010-7E:001F if VALUE > 3"
+func addBreakpoints(sfc core.SuperFamicom, s string) error {
+	for _, b := range strings.Split(s, ";") {
+		b = strings.TrimSpace(b)
+		if b == "" {
+			continue
+		}
+
+		cond := ""
+		if i := strings.Index(b, " if "); i >= 0 {
+			b, cond = b[:i], b[i+4:]
+		}
+		p := strings.Fields(b)
+		if len(p) != 2 {
+			return fmt.Errorf("invalid breakpoint: %s", b)
+		}
+		if !strings.Contains(p[1], "-") {
+			p[1] += "-" + p[1]
+		}
+		r, err := parseRanges(p[1])
+		if err != nil {
+			return err
+		}
+		if _, err := sfc.AddBreakpoint(p[0], r[0], cond); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func printVersion() {
 	fmt.Println(title+":", version)
 }
@@ -5,18 +5,20 @@ import (
 )
 
 // SNES Master cycles -> SPC700's CPU clock cycles
-func toApuCycles(masterCycles int64) float64 {
-	return float64(masterCycles) / 21
+func (a *apu) toApuCycles(masterCycles int64) float64 {
+	return float64(masterCycles) / a.ratio
 }
 
 type apu struct {
 	a.APU
 	cycles float64
+	ratio  float64 // APU_RATIO or APU_RATIO_PAL
 }
 
 func newApu() *apu {
 	a := &apu{
-		APU: a.New(),
+		APU:   a.New(),
+		ratio: APU_RATIO,
 	}
 	return a
 }
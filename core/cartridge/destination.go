@@ -41,3 +41,8 @@ func (d destination) String() string {
 	}
 	return fmt.Sprintf("Unknown(%d)", int(d))
 }
+
+// Japan, America, Korea, Canada and Brazil are NTSC, others are PAL
+func (d destination) pal() bool {
+	return (d >= 0x02 && d <= 0x0C) || d >= 0x11
+}
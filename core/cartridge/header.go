@@ -47,12 +47,22 @@ type Header struct {
 	checksum uint16
 }
 
+// PAL returns true if the game is for PAL region (from destination code)
+func (h *Header) PAL() bool {
+	return h.destination.pal()
+}
+
 func NewHeader(romData []uint8) *Header {
 	h := &Header{}
 	h.load(romData)
 	return h
 }
 
+// DetectROMType detects memory mapping from ROM header (Unknown if no valid header is found)
+func DetectROMType(romData []uint8) RomType {
+	return detectROMType(romData)
+}
+
 func detectROMType(romData []uint8) RomType {
 	switch {
 	case isValidRomHeader(romData, LoROM):
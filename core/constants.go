@@ -114,14 +114,29 @@ const (
 
 // dots span
 const (
-	SCANLINE = 340 // H=0..339
+	SCANLINE  = 340 // H=0..339
+	FIRST_DOT = 22  // Pixels are output at H=22..277
 )
 
 // pixel
 const (
-	HORIZONTAL     = 256
-	VERTICAL       = 224
-	TOTAL_SCANLINE = 262
+	HORIZONTAL         = 256
+	VERTICAL           = 224
+	OVERSCAN           = 239 // SETINI.2
+	TOTAL_SCANLINE     = 262
+	TOTAL_SCANLINE_PAL = 312
+)
+
+const (
+	REGION_AUTO = "AUTO" // detected from ROM header
+	REGION_NTSC = "NTSC"
+	REGION_PAL  = "PAL"
+)
+
+// Master cycles per SPC700 cycle
+const (
+	APU_RATIO     = 21    // 21.477MHz / 1.024MHz
+	APU_RATIO_PAL = 20.78 // 21.281MHz / 1.024MHz
 )
 
 const (
@@ -3,12 +3,14 @@ package core
 import (
 	"errors"
 	"fmt"
+	"image"
 	"math"
 	"os"
 	"runtime"
 	"strings"
 	"unsafe"
 
+	"github.com/pokemium/gsnes/core/disasm"
 	"github.com/pokemium/gsnes/core/scheduler"
 	"github.com/pokemium/iro"
 )
@@ -32,15 +34,35 @@ type SuperFamicom interface {
 	// PC returns current Program counter(Prefetch is ignored)
 	PC() uint32
 
-	// Display resolution
+	// Display resolution of the current frame (width is 512 in hires modes)
 	Resolution() (w int, h int)
 
-	// Return framebuffer represents game screen
+	// Return framebuffer represents game screen (core's own buffer, not copied)
 	FrameBuffer() []iro.RGB555
 
+	// Convert the current frame into buf in the pixel format ("RGBA8888", "BGRA8888", "RGB565") without allocation
+	//
+	// It's a copy with precomputed color tables, not zero-copy. buf needs w*h*PixelSize(format) bytes at least (w, h: Resolution()).
+	ReadFrame(buf []uint8, format string) error
+
 	Pause(p bool)
 	Paused() bool
 
+	// Enable or disable sprite limits per line (32 sprites, 34 tiles), disabling them removes flicker
+	SpriteLimit(enable bool)
+
+	// Video region ("NTSC" or "PAL") of the console
+	Region() string
+
+	// Override video region ("NTSC" or "PAL"), "AUTO" selects it from ROM header
+	SetRegion(region string) error
+
+	// Use dot-based renderer which picks up mid-scanline register writes (slower than default scanline renderer)
+	DotRenderer(enable bool)
+
+	// Enable or disable decoded tile cache (enabled by default, disabling it is for benchmark)
+	TileCache(enable bool)
+
 	// Debug feature
 
 	// Replace builtin memory buffer by your buffer.
@@ -55,6 +77,80 @@ type Debug interface {
 	Status(region string) string
 
 	Stack(depth int) (top uint16, stack []uint8)
+
+	// Render VRAM as a tile sheet (format: "2BPP", "4BPP", "8BPP", "MODE7")
+	//
+	// addr and size are in bytes, palette is the CGRAM palette index for the format.
+	Tiles(format string, addr, size, palette int) (image.Image, error)
+
+	// Write counters of VRAM and CGRAM, viewers need to redraw only when they change
+	VideoWrites() (vram, cgram uint64)
+
+	// Render the whole tilemap of BG1..BG4 with current settings, the scroll viewport is outlined
+	BGMap(index int) (image.Image, error)
+
+	// All 128 sprites in OAM
+	Sprites() []Sprite
+
+	// Render a sprite as displayed
+	SpriteImage(index int) (image.Image, error)
+
+	// OBJ #N shown on the line(1..239) in the latest frame, from the highest priority one
+	ScanlineSprites(line int) []int
+
+	// All 256 colors in CGRAM (BG and OBJ sub-palettes)
+	Palette() Palette
+
+	// Change CGRAM entry while paused
+	SetColor(index int, c iro.RGB555) error
+
+	// CGRAM used to draw the line(1..239) in the latest frame, HDMA palette changes can be seen
+	ScanlineCGRAM(line int) []iro.RGB555
+
+	// Disassemble n instructions from addr with current CPU state (M/X flags, DB, D)
+	Disassemble(addr uint32, n int) []disasm.Instruction
+
+	// Write executed instructions into the file in bsnes or Mesen format ("bsnes", "mesen"), ranges filter PC
+	StartTrace(path, format string, ranges ...AddrRange) error
+
+	// Flush and close the trace log
+	StopTrace() error
+
+	// Pause on execute, read or write ("exec", "read", "write") of the address range if condition is true, returns breakpoint ID
+	AddBreakpoint(kind string, r AddrRange, condition string) (int, error)
+
+	RemoveBreakpoint(id int) error
+
+	Breakpoints() []Breakpoint
+
+	// Breakpoint hit which paused the core (false if paused manually or running)
+	BreakReason() (Break, bool)
+
+	// Subroutines and interrupt handlers being executed, the innermost one first
+	CallStack() []Frame
+
+	// Resume and pause after the instruction at PC, calls are stepped over
+	StepOver()
+
+	// Resume and pause when the current subroutine returns
+	StepOut() error
+
+	// Resume and pause when PC reaches the address
+	RunTo(addr uint32)
+
+	// Resume and pause at the beginning of the scanline
+	RunToScanline(line int) error
+
+	Registers() Registers
+
+	// Change CPU registers while paused
+	SetRegisters(r Registers) error
+
+	// Read system bus without side effects (I/O registers read 0)
+	ReadMemory(addr uint32, size int) []uint8
+
+	// Write system bus while paused
+	WriteMemory(addr uint32, data []uint8) error
 }
 
 type sfc struct {
@@ -67,14 +163,17 @@ type sfc struct {
 	dma       *dmaController
 	m         *memory
 	earlyExit bool
+	region    string // REGION_AUTO, REGION_NTSC or REGION_PAL
+	trace     *tracer
 }
 
 func New() SuperFamicom {
 	sc := scheduler.New() // in SNES, 1 cycle is 1 master cycle.
 	s := &sfc{
-		s:   sc,
-		apu: newApu(),
-		m:   newMemory(),
+		s:      sc,
+		apu:    newApu(),
+		m:      newMemory(),
+		region: REGION_AUTO,
 	}
 	s.ppu = newPpu(s)
 	s.w = new65816(s, &sc.RelativeCycles, &sc.NextEvent)
@@ -105,6 +204,7 @@ func (s *sfc) LoadROM(romData []uint8) error {
 	copy(rom, romData)
 	c := s.w.cart
 	c.loadROM(rom)
+	s.applyRegion()
 	s.Reset()
 	return nil
 }
@@ -113,7 +213,7 @@ func (s *sfc) LoadROM(romData []uint8) error {
 func (s *sfc) RunFrame() {
 	defer s.panicHandler(true)
 
-	const FRAME = SCANLINE * 4 * TOTAL_SCANLINE
+	FRAME := int64(SCANLINE * 4 * s.ppu.totalLines())
 	start := s.s.Cycle()
 
 	old := s.frame
@@ -130,7 +230,8 @@ func (s *sfc) RunFrame() {
 func (s *sfc) RunInst() {
 	old := s.pause
 	s.pause = false
-	defer func() { s.pause = old }()
+	defer func() { s.pause = old || s.pause }()
+	s.w.bkpts.skip = s.w.r.pc.u32() // step over exec breakpoint at PC
 
 	if s.w.checkIrq(NMI) || s.w.checkIrq(IRQ) {
 		return
@@ -201,7 +302,8 @@ func (s *sfc) run() {
 		}
 
 		if s.s.RelativeCycles < s.s.NextEvent {
-			running = s.w.step() && running
+			// finish the current instruction and stop when a read/write breakpoint hits
+			running = s.w.step() && running && !s.pause
 		} else {
 			s.processEvents()
 			running = false
@@ -215,13 +317,10 @@ func (s *sfc) PC() uint32 {
 
 // Display resolution
 func (s *sfc) Resolution() (w int, h int) {
-	return HORIZONTAL, VERTICAL // NTSC
+	return s.ppu.r.width, s.ppu.r.height
 }
 
 func (s *sfc) FrameBuffer() []iro.RGB555 {
-	if s.ppu.inFBlank {
-		return fblankScreen[:]
-	}
 	return s.ppu.r.frameBuffer()
 }
 
@@ -249,12 +348,57 @@ GDMA: %s, HDMA: %s`
 
 func (s *sfc) Pause(p bool) {
 	s.pause = p
+	s.w.bkpts.hit, s.w.bkpts.stop = nil, nil
 }
 
 func (s *sfc) Paused() bool {
 	return s.pause
 }
 
+func (s *sfc) SpriteLimit(enable bool) {
+	s.ppu.r.obj.limit = enable
+}
+
+func (s *sfc) DotRenderer(enable bool) {
+	s.ppu.dot = enable
+}
+
+func (s *sfc) TileCache(enable bool) {
+	s.ppu.vram.cache.disabled = !enable
+	s.ppu.vram.cache.flush()
+}
+
+func (s *sfc) Region() string {
+	if s.ppu.isPAL {
+		return REGION_PAL
+	}
+	return REGION_NTSC
+}
+
+func (s *sfc) SetRegion(region string) error {
+	region = strings.ToUpper(region)
+	switch region {
+	case REGION_AUTO, REGION_NTSC, REGION_PAL:
+		s.region = region
+		s.applyRegion()
+		return nil
+	}
+	return fmt.Errorf("invalid region: %s", region)
+}
+
+func (s *sfc) applyRegion() {
+	pal := s.region == REGION_PAL
+	if s.region == REGION_AUTO {
+		pal = s.w.cart.h.PAL()
+	}
+
+	s.ppu.isPAL, s.ppu.r.isPAL = pal, pal
+	s.apu.ratio = APU_RATIO
+	if pal {
+		s.apu.ratio = APU_RATIO_PAL
+	}
+}
+
 func (s *sfc) Stack(depth int) (uint16, []uint8) {
 	sp := s.w.r.s
 	if depth <= 0 {
@@ -321,6 +465,7 @@ func (s *sfc) MMap(region string, buf []uint8) error {
 		buf16 := (*[VRAM_SIZE / 2]uint16)(unsafe.Pointer(&buf[0]))
 		copy(buf16[:], s.ppu.vram.buf[:])
 		s.ppu.vram.buf = buf16[:]
+		s.ppu.vram.mapped = true
 
 	case "PALETTE":
 		if len(buf) != int(PAL_SIZE) {
@@ -337,6 +482,7 @@ func (s *sfc) MMap(region string, buf []uint8) error {
 
 func (s *sfc) panicHandler(stack bool) {
 	if err := recover(); err != nil {
+		s.StopTrace()
 		fmt.Fprintf(os.Stderr, "Panic in %v\n", s.w.lastInstAddr)
 		fmt.Fprintf(os.Stderr, "         %s\n", err)
 
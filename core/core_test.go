@@ -0,0 +1,75 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/pokemium/gsnes/core/coretest"
+)
+
+// Master cycles between the starts of two VBlanks
+func frameCycles(t *testing.T, s *sfc) int64 {
+	t.Helper()
+	var starts []int64
+	for i := 0; i < 100 && len(starts) < 3; i++ {
+		old := s.frame
+		s.RunFrame()
+		if s.frame != old {
+			starts = append(starts, s.s.Cycle())
+		}
+	}
+	if len(starts) < 3 {
+		t.Fatal("VBlank doesn't start")
+	}
+	return starts[2] - starts[1]
+}
+
+func TestRegion(t *testing.T) {
+	for _, tt := range []struct {
+		dest   uint8 // destination code in ROM header
+		region string
+		lines  int64
+		height int
+	}{
+		{0x00, REGION_NTSC, TOTAL_SCANLINE, VERTICAL},    // Japan
+		{0x01, REGION_NTSC, TOTAL_SCANLINE, VERTICAL},    // America
+		{0x02, REGION_PAL, TOTAL_SCANLINE_PAL, OVERSCAN}, // Europe
+	} {
+		rom := coretest.LoROM(coretest.Counter)
+		coretest.SetDestination(rom, tt.dest)
+		s := New().(*sfc)
+		if err := s.LoadROM(rom); err != nil {
+			t.Fatal(err)
+		}
+		if s.Region() != tt.region {
+			t.Errorf("destination %02X: %s, want %s", tt.dest, s.Region(), tt.region)
+		}
+
+		// VBlank starts after an instruction, so it is rounded to the nearest line
+		n := frameCycles(t, s)
+		if lines := (n + SCANLINE*2) / (SCANLINE * 4); lines != tt.lines {
+			t.Errorf("%s: %d lines (%d cycles) per frame", tt.region, lines, n)
+		}
+		if _, h := s.Resolution(); h != tt.height {
+			t.Errorf("%s: height %d", tt.region, h)
+		}
+		if pal := s.ppu.readIO(0x3F, 0)&0x10 != 0; pal != (tt.region == REGION_PAL) {
+			t.Errorf("%s: STAT78.4 is %v", tt.region, pal)
+		}
+	}
+}
+
+func TestSetRegion(t *testing.T) {
+	s := New().(*sfc)
+	if err := s.LoadROM(coretest.LoROM(coretest.Counter)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetRegion("pal"); err != nil || s.Region() != REGION_PAL {
+		t.Fatalf("%s: %v", s.Region(), err)
+	}
+	if err := s.SetRegion(REGION_AUTO); err != nil || s.Region() != REGION_NTSC {
+		t.Fatalf("%s: %v", s.Region(), err)
+	}
+	if err := s.SetRegion("secam"); err == nil {
+		t.Fatal("invalid region is accepted")
+	}
+}
@@ -0,0 +1,50 @@
+// Package coretest builds small ROM images for tests of the core and its front ends
+package coretest
+
+// LoROM returns a 128KB LoROM image, code is put at 00:8000 and the reset vector points there
+func LoROM(code []uint8) []uint8 {
+	rom := make([]uint8, 0x20000)
+	copy(rom, code)
+	rom[0x7FFC], rom[0x7FFD] = 0x00, 0x80
+	copy(rom[0x7FC0:], "GSNES TEST           ")
+	rom[0x7FD5], rom[0x7FD7] = 0x20, 0x07 // LoROM, 128KB
+	Checksum(rom)
+	return rom
+}
+
+// SetVector sets an interrupt vector (e.g. 0xFFFA: NMI in emulation mode)
+func SetVector(rom []uint8, vector, addr uint16) {
+	ofs := int(vector) - 0x8000
+	rom[ofs], rom[ofs+1] = uint8(addr), uint8(addr>>8)
+	Checksum(rom)
+}
+
+// SetDestination sets destination code of the header (0x00: Japan, 0x01: America, 0x02: Europe)
+func SetDestination(rom []uint8, code uint8) {
+	rom[0x7FD9] = code
+	Checksum(rom)
+}
+
+// Checksum updates the checksum and its complement in the header
+func Checksum(rom []uint8) {
+	rom[0x7FDC], rom[0x7FDD] = 0xFF, 0xFF
+	rom[0x7FDE], rom[0x7FDF] = 0, 0
+	sum := uint16(0)
+	for _, b := range rom {
+		sum += uint16(b)
+	}
+	rom[0x7FDE], rom[0x7FDF] = uint8(sum), uint8(sum>>8)
+	rom[0x7FDC], rom[0x7FDD] = ^uint8(sum), ^uint8(sum>>8)
+}
+
+// Counter program counts up X, and writes it to $10 and a constant to VMDATAL ($2118)
+//
+//	8000: SEI
+//	8001: LDX #$00
+//	8003: INX
+//	8004: STX $10
+//	8006: LDA #$1F
+//	8008: STA $2118
+//	800B: LDA $10
+//	800D: BRA $8003
+var Counter = []uint8{0x78, 0xA2, 0x00, 0xE8, 0x86, 0x10, 0xA9, 0x1F, 0x8D, 0x18, 0x21, 0xA5, 0x10, 0x80, 0xF4}
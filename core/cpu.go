@@ -28,6 +28,7 @@ type w65816 struct {
 	lock int8 // CPU blocked by DMA and DRAM refresh
 
 	bkpts breakpoints
+	calls callStack
 
 	mul struct {
 		a      uint8  // WRMPYA
@@ -77,6 +78,7 @@ func (w *w65816) reset() {
 	entry := w.load16(w.r.vector(RESET), nil)
 	w.r.pc = u24(0, entry)
 	w.wram.reset()
+	w.calls.reset()
 	w.state = CPU_FETCH
 	w.halted = false
 	w.lock = 0
@@ -88,24 +90,30 @@ func (w *w65816) step() (running bool) {
 	switch w.state {
 	case CPU_FETCH:
 		w.lastInstAddr = w.r.pc
-		addCycle(w.cycles, w.wait(w.r.pc))
-		opcode := w.load8(w.r.pc)
-		pushHistory(opcode, w.r.pc)
-
-		if pc := w.lastInstAddr.u32(); w.bkpts.shouldBreak(pc) {
-			for i := range histories {
-				fmt.Println(i, histories[i])
-			}
+		w.calls.sync(w)
+		if w.bkpts.exec > 0 && w.bkpts.shouldBreak(w.r.pc.u32()) {
+			return false
+		}
+		if w.bkpts.stop != nil && w.bkpts.stop(w) {
+			w.bkpts.stop = nil
 			w.c.pause = true
 			return false
 		}
 
+		addCycle(w.cycles, w.wait(w.r.pc))
+		opcode := w.fetch8(w.r.pc)
+		pushHistory(opcode, w.r.pc)
+		w.calls.fetch(w, opcode)
+		if w.c.trace != nil {
+			w.c.trace.log(w.c)
+		}
+
 		w.r.pc.offset++
 		w.inst = opTable[opcode]
 
 	case CPU_READ_PC:
 		addCycle(w.cycles, w.wait(w.r.pc))
-		val := w.load8(w.r.pc)
+		val := w.fetch8(w.r.pc)
 		w.bus.data = val
 		w.r.pc.offset++
 
@@ -123,7 +131,7 @@ func (w *w65816) step() (running bool) {
 	w.state = CPU_FETCH
 	w.inst(w)
 
-	w.c.apu.cycles += toApuCycles(*w.cycles - prev)
+	w.c.apu.cycles += w.c.apu.toApuCycles(*w.cycles - prev)
 	return true
 }
 
@@ -160,6 +168,7 @@ func (w *w65816) checkIrq(e exception) (interrupted bool) {
 	}
 
 	w.halted = false
+	w.calls.interrupt(w, e)
 	if w.r.emulation {
 		w.r.p.x = false
 		fn(w)
@@ -196,6 +205,15 @@ func (w *w65816) wait(addr uint24) int64 {
 
 // Load a byte from memory.
 func (w *w65816) load8(addr uint24) uint8 {
+	val := w.fetch8(addr)
+	if w.bkpts.read > 0 {
+		w.bkpts.check(BREAK_READ, addr.u32(), val)
+	}
+	return val
+}
+
+// Load opcode or operand, it's not checked by read breakpoints.
+func (w *w65816) fetch8(addr uint24) uint8 {
 	m := w.c.m
 	m.before = uint(addr.u32())
 	return m.reader[m.lookup[addr.u32()]](m.target[addr.u32()], w.bus.data)
@@ -218,6 +236,9 @@ func (w *w65816) store8(addr uint24, val uint8, cycles *int64) {
 	m := w.c.m
 	m.before = uint(addr.u32())
 	m.writer[m.lookup[addr.u32()]](m.target[addr.u32()], val)
+	if w.bkpts.write > 0 {
+		w.bkpts.check(BREAK_WRITE, addr.u32(), val)
+	}
 }
 
 func (w *w65816) read8(addr uint24, fn func(uint8)) {
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/pokemium/gsnes/core/coretest"
+)
+
+// Instructions which don't go to the next instruction
+var controlFlow = map[string]bool{
+	"BRK": true, "COP": true, "JMP": true, "JML": true, "JSR": true, "JSL": true,
+	"RTS": true, "RTL": true, "RTI": true, "BRA": true, "BRL": true,
+	"BCC": true, "BCS": true, "BEQ": true, "BNE": true, "BMI": true, "BPL": true, "BVC": true, "BVS": true,
+	"WAI": true, "STP": true, "MVN": true, "MVP": true,
+}
+
+// Instruction size decoded by disasm (opcodes table) must be the same as PC advanced by opTable
+func TestOpTableSize(t *testing.T) {
+	modes := []struct {
+		name   string
+		prefix []uint8
+	}{
+		{"emulation", nil},
+		{"native M=0 X=0", []uint8{0x18, 0xFB, 0xC2, 0x30}}, // CLC; XCE; REP #$30
+		{"native M=1 X=0", []uint8{0x18, 0xFB, 0xC2, 0x10}}, // CLC; XCE; REP #$10
+		{"native M=0 X=1", []uint8{0x18, 0xFB, 0xC2, 0x20}}, // CLC; XCE; REP #$20
+	}
+
+	for _, m := range modes {
+		s := New().(*sfc)
+		if err := s.LoadROM(coretest.LoROM(m.prefix)); err != nil {
+			t.Fatal(err)
+		}
+		s.Pause(true)
+		for s.PC() < 0x008000+uint32(len(m.prefix)) {
+			s.RunInst()
+		}
+		regs := s.w.r
+		regs.pc = u24(0x7E, 0x1000) // opcodes are put in WRAM
+
+		for op := 0; op < 256; op++ {
+			copy(s.w.wram.buf[0x1000:], []uint8{uint8(op), 0x10, 0x00, 0x00})
+			s.w.r = regs
+
+			inst := s.Disassemble(regs.pc.u32(), 1)[0]
+			if controlFlow[inst.Name] {
+				continue
+			}
+			s.RunInst()
+			if got := int(s.PC() - regs.pc.u32()); got != inst.Size() {
+				t.Errorf("%s: %02X %s: PC advanced %d bytes, disasm size is %d", m.name, op, inst, got, inst.Size())
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/pokemium/iro"
+)
+
+// BGMap renders the whole tilemap of BG1..BG4 with current BG settings
+//
+// The scroll viewport is outlined by inverted colors, in Mode 7 it is the screen transformed by M7A..M7D.
+func (s *sfc) BGMap(index int) (image.Image, error) {
+	r := s.ppu.r
+	if index < 1 || index > 4 {
+		return nil, fmt.Errorf("invalid BG: %d", index)
+	}
+	b := [4]*bg{r.bg1, r.bg2, r.bg3, r.bg4}[index-1]
+	if !b.enable() {
+		return nil, fmt.Errorf("BG%d is disabled in mode %d", index, r.mode)
+	}
+
+	mod := func(n, m int) int { return ((n % m) + m) % m }
+	vh := r.lines
+
+	if r.mode == 7 {
+		buf, w, h := b.mode7Map()
+
+		// the viewport is a quad(or a line) in the map, its edges are inverted once even if they overlap
+		edge := make(map[int]bool)
+		line := func(x0, y0, x1, y1 int) {
+			dx, dy := x1-x0, y1-y0
+			n := 1
+			for _, d := range [2]int{dx, dy} {
+				if d > n {
+					n = d
+				} else if -d > n {
+					n = -d
+				}
+			}
+			for i := 0; i <= n; i++ {
+				x, y := x0+dx*i/n, y0+dy*i/n
+				edge[mod(y, h)*w+mod(x, w)] = true
+			}
+		}
+		m := &r.m7
+		corners := [4][2]int{{0, 1}, {HORIZONTAL - 1, 1}, {HORIZONTAL - 1, vh}, {0, vh}}
+		for i, p := range corners {
+			q := corners[(i+1)%4]
+			x0, y0 := m.transform(p[0], p[1])
+			x1, y1 := m.transform(q[0], q[1])
+			line(x0, y0, x1, y1)
+		}
+		for i := range edge {
+			buf[i] = ^buf[i] & 0x7FFF
+		}
+		return iro.RGB555ToImage(buf, w, h, nil), nil
+	}
+
+	buf, w, h, err := b.tilemap()
+	if err != nil {
+		return nil, err
+	}
+	scx, scy, vw := int(b.sc[0].val), int(b.sc[1].val), HORIZONTAL
+	if r.hires() {
+		scx, vw = scx*2, HORIZONTAL*2
+	}
+
+	// viewport
+	invert := func(x, y int) {
+		i := mod(y, h)*w + mod(x, w)
+		buf[i] = ^buf[i] & 0x7FFF
+	}
+	for i := 0; i < vw; i++ {
+		invert(scx+i, scy)
+		invert(scx+i, scy+vh-1)
+	}
+	for i := 1; i < vh-1; i++ {
+		invert(scx, scy+i)
+		invert(scx+vw-1, scy+i)
+	}
+
+	return iro.RGB555ToImage(buf, w, h, nil), nil
+}
+
+// Whole tilemap of BG (Mode0..6)
+func (b *bg) tilemap() ([]iro.RGB555, int, int, error) {
+	r := b.r
+	var format string
+	switch b.color {
+	case COLOR_2BPP:
+		format = TILE_2BPP
+	case COLOR_4BPP:
+		format = TILE_4BPP
+	case COLOR_8BPP:
+		format = TILE_8BPP
+	default:
+		return nil, 0, 0, fmt.Errorf("unsupported BG color depth in mode %d: %d", r.mode, b.color)
+	}
+	colors := 1 << tileBpp(format) // colors per palette
+
+	tw, th := b.tilesize, b.tilesize
+	if r.hires() {
+		tw = 16
+	}
+	w, h := int(b.size[0]*tw), int(b.size[1]*th)
+	buf := make([]iro.RGB555, w*h)
+
+	// 8x8ずつ描画
+	for y := 0; y < h; y += 8 {
+		for x := 0; x < w; x += 8 {
+			entry := b.entry(uint16(x)/tw, uint16(y)/th)
+			palID := int((entry >> 10) & 0b111)
+			hflip, vflip := bit(entry, 14), bit(entry, 15)
+
+			quadrant := 0
+			if tw == 16 && (x%16 >= 8) != hflip {
+				quadrant += 1
+			}
+			if th == 16 && (y%16 >= 8) != vflip {
+				quadrant += 2
+			}
+			tileID := (entry & 1023) + ofs16[quadrant]
+			tileStart := (b.tileDataAddr/2 + uint(tileBytes(format)/2)*uint(tileID)) & 0x7FFF
+
+			for py := 0; py < 8; py++ {
+				for px := 0; px < 8; px++ {
+					colorID := r.vram.pixel(format, tileStart, flip(8, hflip, px), flip(8, vflip, py))
+					c := r.pal.buf[0]
+					switch {
+					case colorID == 0:
+					case b.color == COLOR_8BPP && r.math.direct:
+						c = directColor(uint8(palID), colorID)
+					case b.color == COLOR_8BPP:
+						c = r.pal.buf[colorID]
+					default:
+						c = r.pal.buf[b.palOfs+palID*colors+int(colorID)]
+					}
+					buf[(y+py)*w+x+px] = c
+				}
+			}
+		}
+	}
+	return buf, w, h, nil
+}
+
+// Whole 1024x1024 map of Mode7
+func (b *bg) mode7Map() ([]iro.RGB555, int, int) {
+	r := b.r
+	const size = 1024
+	buf := make([]iro.RGB555, size*size)
+	for row := 0; row < 128; row++ {
+		for col := 0; col < 128; col++ {
+			tile := uint(r.vram.buf[row*128+col] & 0xFF)
+			for py := 0; py < 8; py++ {
+				for px := 0; px < 8; px++ {
+					colorID := r.vram.pixel(TILE_MODE7, tile*64, px, py)
+					if b.index == 2 {
+						colorID &= 0x7F // EXTBG
+					}
+					c := r.pal.buf[colorID]
+					if colorID != 0 && b.index == 1 && r.math.direct {
+						c = directColor(0, colorID)
+					}
+					buf[(row*8+py)*size+col*8+px] = c
+				}
+			}
+		}
+	}
+	return buf, size, size
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestBGMapMode7Viewport(t *testing.T) {
+	s := New().(*sfc)
+	p := s.ppu
+	p.reset()
+	p.writeIO(0x05, 7) // BGMODE
+	for _, r := range []uint{0x1B, 0x1E} {
+		p.writeIO(r, 0x00) // M7A, M7D: 2.0 (zoom out)
+		p.writeIO(r, 0x02)
+	}
+	p.writeIO(0x0D, 0x10) // M7HOFS: 16 (32 in the map)
+	p.writeIO(0x0D, 0x00)
+	p.r.lines = VERTICAL
+
+	img, err := s.BGMap(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	white := color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	for _, tt := range []struct {
+		x, y int
+		edge bool
+	}{
+		{32, 2, true}, {32 + 255*2, 2, true}, {32 + 100, 2 * VERTICAL, true}, {32, 300, true},
+		{32 + 256*2, 2, false}, {32 + 100, 300, false}, {16, 300, false},
+	} {
+		got := img.At(tt.x, tt.y) == white
+		if got != tt.edge {
+			t.Errorf("(%d, %d): edge %v, want %v", tt.x, tt.y, got, tt.edge)
+		}
+	}
+}
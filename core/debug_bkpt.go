@@ -1,32 +1,215 @@
 package core
 
-// TODO: 開発用
+import (
+	"fmt"
+	"strings"
+)
+
+// Breakpoint kinds
+const (
+	BREAK_EXEC  = "exec"  // CPU fetches opcode
+	BREAK_READ  = "read"  // CPU or DMA reads (opcode and operand fetches are not included)
+	BREAK_WRITE = "write" // CPU or DMA writes
+)
+
+// Breakpoint pauses the core when an address in Range is accessed and Condition is true
+//
+// Mirrors are also hit (e.g. 80:8000 and 00:8000 in LoROM, 00:0010 and 7E:0010), see memory.mirror.
+type Breakpoint struct {
+	ID        int
+	Kind      string
+	Range     AddrRange
+	Condition string // empty: always
+}
+
+// Break describes the breakpoint hit that paused the core
+type Break struct {
+	Breakpoint
+	Addr  uint32 // PC (exec) or accessed address
+	Value uint8  // byte read or written
+	PC    uint32 // instruction being executed when hit
+}
+
+func (b Break) String() string {
+	if b.Kind == BREAK_EXEC {
+		return fmt.Sprintf("exec breakpoint #%d at %02X:%04X", b.ID, b.Addr>>16, uint16(b.Addr))
+	}
+	return fmt.Sprintf("%s breakpoint #%d at %02X:%04X = $%02X (PC %02X:%04X)", b.Kind, b.ID, b.Addr>>16, uint16(b.Addr), b.Value, b.PC>>16, uint16(b.PC))
+}
+
+type breakpoint struct {
+	Breakpoint
+	cond cond
+}
 
 type breakpoints struct {
-	c     *sfc
-	slice []uint32
-	last  uint32
+	c      *sfc
+	list   []breakpoint
+	nextID int
+
+	// number of breakpoints by kind, memory access hooks are skipped if zero
+	exec, read, write int
+
+	// PC which the core paused at, exec breakpoints there are ignored once so that it can be resumed
+	skip uint32
+
+	hit *Break
+
+	// stop condition of step over, step out and run-to, checked on opcode fetch
+	stop func(w *w65816) bool
 }
 
-func newBreakpoints(c *sfc, addrs ...uint32) *breakpoints {
+const noBreak = uint32(0xffffffff)
+
+func newBreakpoints(c *sfc) *breakpoints {
 	return &breakpoints{
-		c:     c,
-		slice: addrs,
-		last:  uint32(0xffffffff),
+		c:      c,
+		nextID: 1,
+		skip:   noBreak,
+	}
+}
+
+func (b *breakpoints) count(kind string) *int {
+	switch kind {
+	case BREAK_EXEC:
+		return &b.exec
+	case BREAK_READ:
+		return &b.read
+	case BREAK_WRITE:
+		return &b.write
+	}
+	return nil
+}
+
+// Check exec breakpoints at opcode fetch
+func (b *breakpoints) shouldBreak(pc uint32) bool {
+	skip := b.skip
+	b.skip = noBreak
+	if pc == skip {
+		return false
+	}
+	if b.check(BREAK_EXEC, pc, 0) {
+		b.skip = pc
+		return true
+	}
+	return false
+}
+
+// Check breakpoints of the kind and pause the core if any hits
+func (b *breakpoints) check(kind string, addr uint32, val uint8) bool {
+	for i := range b.list {
+		bp := &b.list[i]
+		if bp.Kind != kind || !b.c.m.inRange(bp.Range, addr) {
+			continue
+		}
+		if bp.cond != nil && bp.cond(b.c, access{addr, val}) == 0 {
+			continue
+		}
+
+		// keep the first hit until the current instruction is finished
+		if !b.c.pause {
+			b.hit = &Break{
+				Breakpoint: bp.Breakpoint,
+				Addr:       addr,
+				Value:      val,
+				PC:         b.c.w.lastInstAddr.u32(),
+			}
+			b.c.pause = true
+			b.stop = nil
+		}
+		return true
+	}
+	return false
+}
+
+// inRange checks whether addr or its mirror is in r
+func (m *memory) inRange(r AddrRange, addr uint32) bool {
+	if r.contains(addr) {
+		return true
+	}
+
+	// the range is compared by mirrors only if it isn't split by them
+	start, end := m.mirror(r.Start), m.mirror(r.End)
+	return end-start == r.End-r.Start && AddrRange{start, end}.contains(m.mirror(addr))
+}
+
+// mirror returns the address which addr mirrors
+//
+// Banks 80-FF are folded into 00-7F, system area (0000-7FFF) into bank 00 and LowRAM (00:0000-1FFF) into 7E
+// when they are mapped to the same memory.
+func (m *memory) mirror(addr uint32) uint32 {
+	a := uint(addr) & 0xFF_FFFF
+	if a >= 0x80_0000 && m.same(a, a-0x80_0000) {
+		a -= 0x80_0000
+	}
+	if ofs := a & 0xFFFF; ofs < 0x8000 && m.same(a, ofs) {
+		a = ofs
+	}
+	if a < 0x2000 && m.lookup[a] != 0 {
+		a |= 0x7E_0000
+	}
+	return uint32(a)
+}
+
+func (m *memory) same(a, b uint) bool {
+	return m.lookup[a] != 0 && m.lookup[a] == m.lookup[b] && m.target[a] == m.target[b]
+}
+
+// AddBreakpoint pauses the core when CPU executes (BREAK_EXEC), reads (BREAK_READ) or writes (BREAK_WRITE) an address in r
+//
+// I/O registers such as PPU (21xx) and DMA (43xx) ports can be watched, and DMA transfers are also checked.
+// condition is evaluated when the address is accessed (e.g. "A == $1F && [7E0010] > 3", syntax is in debug_cond.go).
+func (s *sfc) AddBreakpoint(kind string, r AddrRange, condition string) (int, error) {
+	kind = strings.ToLower(kind)
+	b := &s.w.bkpts
+	n := b.count(kind)
+	if n == nil {
+		return 0, fmt.Errorf("invalid breakpoint kind: %s", kind)
 	}
+	if r.Start > r.End || r.End > 0xFF_FFFF {
+		return 0, fmt.Errorf("invalid address range: %06X-%06X", r.Start, r.End)
+	}
+	c, err := parseCond(condition)
+	if err != nil {
+		return 0, err
+	}
+
+	bp := breakpoint{
+		Breakpoint: Breakpoint{ID: b.nextID, Kind: kind, Range: r, Condition: strings.TrimSpace(condition)},
+		cond:       c,
+	}
+	b.list = append(b.list, bp)
+	b.nextID++
+	*n++
+	return bp.ID, nil
 }
 
-func (b *breakpoints) shouldBreak(pc uint32) (isBreak bool) {
-	defer func() {
-		b.last = pc
-	}()
-	for _, addr := range b.slice {
-		if pc == addr {
-			isBreak = true
-			break
+// RemoveBreakpoint deletes the breakpoint by ID
+func (s *sfc) RemoveBreakpoint(id int) error {
+	b := &s.w.bkpts
+	for i := range b.list {
+		if b.list[i].ID == id {
+			*b.count(b.list[i].Kind)--
+			b.list = append(b.list[:i], b.list[i+1:]...)
+			return nil
 		}
 	}
+	return fmt.Errorf("breakpoint #%d not found", id)
+}
+
+// Breakpoints returns all breakpoints in the order they were added
+func (s *sfc) Breakpoints() []Breakpoint {
+	result := make([]Breakpoint, len(s.w.bkpts.list))
+	for i, bp := range s.w.bkpts.list {
+		result[i] = bp.Breakpoint
+	}
+	return result
+}
 
-	isBreak = isBreak && pc != b.last
-	return isBreak
+// BreakReason returns the breakpoint hit which paused the core
+func (s *sfc) BreakReason() (Break, bool) {
+	if !s.pause || s.w.bkpts.hit == nil {
+		return Break{}, false
+	}
+	return *s.w.bkpts.hit, true
 }
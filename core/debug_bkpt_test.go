@@ -0,0 +1,168 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/pokemium/gsnes/core/coretest"
+)
+
+// coretest.Counter loops over 8003..800D
+func newBkptTest(t *testing.T) *sfc {
+	t.Helper()
+	s := New().(*sfc)
+	if err := s.LoadROM(coretest.LoROM(coretest.Counter)); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// Run frames until a breakpoint is hit
+func runToBreak(t *testing.T, s *sfc) Break {
+	t.Helper()
+	for i := 0; i < 3 && !s.Paused(); i++ {
+		s.RunFrame()
+	}
+	b, ok := s.BreakReason()
+	if !ok {
+		t.Fatalf("breakpoint isn't hit (PC: %06X)", s.PC())
+	}
+	return b
+}
+
+func TestExecBreakpoint(t *testing.T) {
+	s := newBkptTest(t)
+	id, err := s.AddBreakpoint(BREAK_EXEC, AddrRange{0x008006, 0x008006}, "[7E0010] > 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := runToBreak(t, s)
+	if b.ID != id || b.Kind != BREAK_EXEC || b.Addr != 0x008006 || s.PC() != 0x008006 || s.w.r.x != 4 {
+		t.Fatalf("%v: PC %06X, X %d", b, s.PC(), s.w.r.x)
+	}
+
+	// resuming ignores the breakpoint at PC once, so it is hit again on the next loop
+	s.Pause(false)
+	b = runToBreak(t, s)
+	if b.Addr != 0x008006 || s.PC() != 0x008006 || s.w.r.x != 5 {
+		t.Fatalf("%v: PC %06X, X %d", b, s.PC(), s.w.r.x)
+	}
+
+	// step from the breakpoint
+	s.RunInst()
+	if !s.Paused() || s.PC() != 0x008008 {
+		t.Fatalf("RunInst: PC %06X, paused %v", s.PC(), s.Paused())
+	}
+}
+
+func TestExecBreakpointResume(t *testing.T) {
+	s := newBkptTest(t)
+	if _, err := s.AddBreakpoint(BREAK_EXEC, AddrRange{0x008003, 0x008003}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for x := uint16(0); x < 3; x++ {
+		runToBreak(t, s)
+		if s.PC() != 0x008003 || s.w.r.x != x {
+			t.Fatalf("PC %06X, X %d, want X %d", s.PC(), s.w.r.x, x)
+		}
+		s.Pause(false)
+	}
+}
+
+func TestWriteBreakpoint(t *testing.T) {
+	s := newBkptTest(t)
+	if _, err := s.AddBreakpoint(BREAK_WRITE, AddrRange{0x002100, 0x00213F}, "VALUE == $1F && X >= 8"); err != nil {
+		t.Fatal(err)
+	}
+
+	// STA $2118 is finished before the core pauses
+	b := runToBreak(t, s)
+	if b.Kind != BREAK_WRITE || b.Addr != 0x002118 || b.Value != 0x1F || b.PC != 0x008008 {
+		t.Fatalf("%v", b)
+	}
+	if s.PC() != 0x00800B || s.w.r.x != 8 {
+		t.Fatalf("PC %06X, X %d", s.PC(), s.w.r.x)
+	}
+}
+
+func TestReadBreakpoint(t *testing.T) {
+	s := newBkptTest(t)
+	if _, err := s.AddBreakpoint(BREAK_READ, AddrRange{0x000010, 0x000010}, "VALUE == 3"); err != nil {
+		t.Fatal(err)
+	}
+
+	// LDA $10 is finished, opcode and operand fetches of the program don't hit
+	b := runToBreak(t, s)
+	if b.Kind != BREAK_READ || b.Addr != 0x000010 || b.Value != 3 || b.PC != 0x00800B {
+		t.Fatalf("%v", b)
+	}
+	if s.PC() != 0x00800D || s.w.r.a&0xFF != 3 {
+		t.Fatalf("PC %06X, A %04X", s.PC(), s.w.r.a)
+	}
+
+	// mirror is hit too
+	if err := s.RemoveBreakpoint(b.ID); err != nil {
+		t.Fatal(err)
+	}
+	s.AddBreakpoint(BREAK_READ, AddrRange{0x7E0010, 0x7E0010}, "VALUE == 4")
+	s.Pause(false)
+	b = runToBreak(t, s)
+	if b.Addr != 0x000010 || b.Value != 4 {
+		t.Fatalf("%v", b)
+	}
+}
+
+func TestBreakpointMirror(t *testing.T) {
+	s := newBkptTest(t)
+	if _, err := s.AddBreakpoint(BREAK_EXEC, AddrRange{0x808006, 0x808006}, ""); err != nil {
+		t.Fatal(err)
+	}
+	b := runToBreak(t, s)
+	if b.Addr != 0x008006 || s.PC() != 0x008006 {
+		t.Fatalf("%v: PC %06X", b, s.PC())
+	}
+
+	s.RemoveBreakpoint(b.ID)
+	if _, err := s.AddBreakpoint(BREAK_WRITE, AddrRange{0xBF2118, 0xBF2119}, ""); err != nil {
+		t.Fatal(err)
+	}
+	s.Pause(false)
+	if b = runToBreak(t, s); b.Addr != 0x002118 {
+		t.Fatalf("%v", b)
+	}
+
+	m := s.m
+	for _, tt := range []struct{ addr, want uint32 }{
+		{0x808000, 0x008000},
+		{0x001FFF, 0x7E1FFF},
+		{0x3F0010, 0x7E0010},
+		{0x7F0010, 0x7F0010},
+		{0x012140, 0x002140},
+		{0xC08000, 0x408000},
+		{0xFF0000, 0xFF0000},
+	} {
+		if got := m.mirror(tt.addr); got != tt.want {
+			t.Errorf("mirror(%06X) = %06X, want %06X", tt.addr, got, tt.want)
+		}
+	}
+	if m.inRange(AddrRange{0x7E1F00, 0x7E20FF}, 0x002000) {
+		t.Error("00:2000 is in 7E:1F00-20FF")
+	}
+}
+
+func TestBreakpointError(t *testing.T) {
+	s := newBkptTest(t)
+	if _, err := s.AddBreakpoint("jump", AddrRange{0x008000, 0x008000}, ""); err == nil {
+		t.Error("unknown kind is added")
+	}
+	if _, err := s.AddBreakpoint(BREAK_EXEC, AddrRange{0x008000, 0x008000}, "A =="); err == nil {
+		t.Error("invalid condition is added")
+	}
+	if err := s.RemoveBreakpoint(100); err == nil {
+		t.Error("unknown breakpoint is removed")
+	}
+	if len(s.Breakpoints()) != 0 {
+		t.Errorf("%v", s.Breakpoints())
+	}
+}
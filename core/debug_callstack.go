@@ -0,0 +1,109 @@
+package core
+
+import "fmt"
+
+// Frame is an entry of CPU call stack
+type Frame struct {
+	Kind   string // "JSR", "JSL", "BRK", "COP", "NMI", "IRQ"
+	Caller uint32 // address of the call instruction (interrupt: interrupted instruction)
+	Entry  uint32 // subroutine or interrupt handler
+	Return uint32 // address where the CPU returns to
+	SP     uint16 // stack pointer before the call
+}
+
+func (f Frame) String() string {
+	return fmt.Sprintf("%-3s %02X:%04X -> %02X:%04X (ret %02X:%04X)", f.Kind, f.Caller>>16, uint16(f.Caller), f.Entry>>16, uint16(f.Entry), f.Return>>16, uint16(f.Return))
+}
+
+const MAX_CALL_DEPTH = 256
+
+// Call stack tracked on opcode fetch
+//
+// Frames are popped by RTS, RTL and RTI, and also dropped when SP goes above them (e.g. return address is discarded by PLA).
+type callStack struct {
+	frames  []Frame
+	pending *Frame // call instruction being executed, its entry is known at the next fetch
+	ret     bool   // return instruction being executed
+}
+
+func (c *callStack) reset() {
+	c.frames = c.frames[:0]
+	c.pending, c.ret = nil, false
+}
+
+// Called on opcode fetch
+func (c *callStack) fetch(w *w65816, opcode uint8) {
+	pc := w.r.pc.u32()
+	switch opcode {
+	case 0x20, 0xFC: // JSR
+		c.call(w, "JSR", pc, u24(w.r.pc.bank, w.r.pc.offset+3).u32())
+	case 0x22: // JSL
+		c.call(w, "JSL", pc, u24(w.r.pc.bank, w.r.pc.offset+4).u32())
+	case 0x00: // BRK
+		c.call(w, "BRK", pc, u24(w.r.pc.bank, w.r.pc.offset+2).u32())
+	case 0x02: // COP
+		c.call(w, "COP", pc, u24(w.r.pc.bank, w.r.pc.offset+2).u32())
+	case 0x60, 0x6B, 0x40: // RTS, RTL, RTI
+		c.ret = true
+	}
+}
+
+// Called when NMI or IRQ is taken (before the return address is pushed)
+func (c *callStack) interrupt(w *w65816, e exception) {
+	c.sync(w)
+
+	kind := "IRQ"
+	if e == NMI {
+		kind = "NMI"
+	}
+	pc := w.r.pc.u32()
+	c.call(w, kind, pc, pc)
+}
+
+func (c *callStack) call(w *w65816, kind string, caller, ret uint32) {
+	c.pending = &Frame{Kind: kind, Caller: caller, Return: ret, SP: w.r.s}
+}
+
+// Complete the previous instruction (called before opcode fetch)
+func (c *callStack) sync(w *w65816) {
+	if f := c.pending; f != nil {
+		c.pending = nil
+		c.drop(f.SP)
+		f.Entry = w.r.pc.u32()
+		if len(c.frames) == MAX_CALL_DEPTH {
+			c.frames = append(c.frames[:0], c.frames[1:]...)
+		}
+		c.frames = append(c.frames, *f)
+	}
+	if c.ret {
+		c.ret = false
+		c.drop(w.r.s)
+	}
+}
+
+// Drop frames which SP is above
+func (c *callStack) drop(sp uint16) {
+	n := len(c.frames)
+	for n > 0 && c.frames[n-1].SP <= sp {
+		n--
+	}
+	c.frames = c.frames[:n]
+}
+
+// Call stack including the call or return which has just been executed
+func (s *sfc) callStack() *callStack {
+	if s.w.state == CPU_FETCH {
+		s.w.calls.sync(s.w)
+	}
+	return &s.w.calls
+}
+
+// CallStack returns subroutines and interrupt handlers being executed, the innermost one first
+func (s *sfc) CallStack() []Frame {
+	frames := s.callStack().frames
+	result := make([]Frame, len(frames))
+	for i := range frames {
+		result[i] = frames[len(frames)-1-i]
+	}
+	return result
+}
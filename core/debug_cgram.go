@@ -0,0 +1,61 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/pokemium/iro"
+)
+
+// Color is a CGRAM entry for debugger
+type Color struct {
+	RGB555  iro.RGB555
+	R, G, B uint8 // 8bit
+}
+
+func newColor(c iro.RGB555) Color {
+	rgba := c.Color()
+	return Color{RGB555: c, R: rgba.R, G: rgba.G, B: rgba.B}
+}
+
+// Palette is whole CGRAM grouped into 16-color sub-palettes
+//
+// BG is CGRAM[0..127] (2bpp BGs use 4-color slices of them), OBJ is CGRAM[128..255].
+type Palette struct {
+	BG  [8][16]Color
+	OBJ [8][16]Color
+}
+
+func (s *sfc) Palette() Palette {
+	buf := s.ppu.pal.buf
+	p := Palette{}
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 16; j++ {
+			p.BG[i][j] = newColor(buf[i*16+j])
+			p.OBJ[i][j] = newColor(buf[0x80+i*16+j])
+		}
+	}
+	return p
+}
+
+// SetColor changes CGRAM[index], only while the core is paused so that it doesn't race with CPU and HDMA
+func (s *sfc) SetColor(index int, c iro.RGB555) error {
+	if !s.pause {
+		return fmt.Errorf("palette can be edited only while paused")
+	}
+	if index < 0 || index >= len(s.ppu.pal.buf) {
+		return fmt.Errorf("invalid CGRAM index: %d", index)
+	}
+	s.ppu.pal.buf[index] = c & 0x7FFF
+	s.ppu.pal.writes++
+	return nil
+}
+
+// ScanlineCGRAM returns a copy of CGRAM used to draw the line(1..239) in the latest frame
+func (s *sfc) ScanlineCGRAM(line int) []iro.RGB555 {
+	if line < 1 || line > OVERSCAN {
+		return []iro.RGB555{}
+	}
+	result := make([]iro.RGB555, len(s.ppu.r.cgram[line-1]))
+	copy(result, s.ppu.r.cgram[line-1][:])
+	return result
+}
@@ -0,0 +1,313 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Breakpoint condition
+//
+//	A == $1F && [7E0010] > 3
+//
+// Operands:
+//   - Registers: A, X, Y (8bit when M/X flag is set), C (16bit accumulator), S, D, DB, PB, PC, P
+//   - Flags: FN, FV, FM, FX, FD, FI, FZ, FC, FE (1 or 0)
+//   - VALUE, ADDR: byte and address of the memory access (read/write breakpoint)
+//   - [7E0010]: byte in memory, address is hex (I/O registers read 0)
+//   - Numbers: $1F, 0x1F, 31
+//
+// Operators follow Go precedence: ! - ^ (unary), * / % << >> &, + - | ^, == != < <= > >=, &&, ||
+type cond func(s *sfc, a access) int64
+
+// Memory access that triggers a breakpoint
+type access struct {
+	addr  uint32
+	value uint8
+}
+
+var condOperands = map[string]cond{
+	"A": func(s *sfc, _ access) int64 {
+		if s.w.r.p.m {
+			return int64(s.w.r.a & 0xFF)
+		}
+		return int64(s.w.r.a)
+	},
+	"C": func(s *sfc, _ access) int64 { return int64(s.w.r.a) },
+	"X": func(s *sfc, _ access) int64 {
+		if s.w.r.p.x {
+			return int64(s.w.r.x & 0xFF)
+		}
+		return int64(s.w.r.x)
+	},
+	"Y": func(s *sfc, _ access) int64 {
+		if s.w.r.p.x {
+			return int64(s.w.r.y & 0xFF)
+		}
+		return int64(s.w.r.y)
+	},
+	"S":     func(s *sfc, _ access) int64 { return int64(s.w.r.s) },
+	"D":     func(s *sfc, _ access) int64 { return int64(s.w.r.d) },
+	"DB":    func(s *sfc, _ access) int64 { return int64(s.w.r.db) },
+	"PB":    func(s *sfc, _ access) int64 { return int64(s.w.lastInstAddr.bank) },
+	"PC":    func(s *sfc, _ access) int64 { return int64(s.w.lastInstAddr.u32()) },
+	"P":     func(s *sfc, _ access) int64 { return int64(s.w.r.p.pack()) },
+	"FN":    func(s *sfc, _ access) int64 { return b2i(s.w.r.p.n) },
+	"FV":    func(s *sfc, _ access) int64 { return b2i(s.w.r.p.v) },
+	"FM":    func(s *sfc, _ access) int64 { return b2i(s.w.r.p.m) },
+	"FX":    func(s *sfc, _ access) int64 { return b2i(s.w.r.p.x) },
+	"FD":    func(s *sfc, _ access) int64 { return b2i(s.w.r.p.d) },
+	"FI":    func(s *sfc, _ access) int64 { return b2i(s.w.r.p.i) },
+	"FZ":    func(s *sfc, _ access) int64 { return b2i(s.w.r.p.z) },
+	"FC":    func(s *sfc, _ access) int64 { return b2i(s.w.r.p.c) },
+	"FE":    func(s *sfc, _ access) int64 { return b2i(s.w.r.emulation) },
+	"VALUE": func(_ *sfc, a access) int64 { return int64(a.value) },
+	"ADDR":  func(_ *sfc, a access) int64 { return int64(a.addr) },
+}
+
+func b2i(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// binary operators by precedence (Go spec)
+var condBinary = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<=", ">=", "<", ">"},
+	{"+", "-", "|", "^"},
+	{"*", "/", "%", "<<", ">>", "&"},
+}
+
+type condParser struct {
+	src  string
+	toks []string
+	pos  int
+}
+
+// parseCond compiles condition expression, empty string is always true
+func parseCond(src string) (cond, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	toks, err := condTokens(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &condParser{src: src, toks: toks}
+	c, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("condition %q: unexpected %q", src, p.toks[p.pos])
+	}
+	return c, nil
+}
+
+func condTokens(src string) ([]string, error) {
+	toks := []string{}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '[':
+			end := strings.IndexByte(src[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("condition %q: missing ']'", src)
+			}
+			toks = append(toks, src[i:i+end+1])
+			i += end + 1
+		case isWord(c) || c == '$':
+			j := i + 1
+			for j < len(src) && isWord(src[j]) {
+				j++
+			}
+			toks = append(toks, src[i:j])
+			i = j
+		default:
+			if i+1 < len(src) {
+				switch op := src[i : i+2]; op {
+				case "||", "&&", "==", "!=", "<=", ">=", "<<", ">>":
+					toks = append(toks, op)
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("()!<>+-*/%&|^", rune(c)) {
+				return nil, fmt.Errorf("condition %q: invalid character %q", src, c)
+			}
+			toks = append(toks, string(c))
+			i++
+		}
+	}
+	return toks, nil
+}
+
+func isWord(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func (p *condParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *condParser) binary(prec int) (cond, error) {
+	if prec == len(condBinary) {
+		return p.unary()
+	}
+	lhs, err := p.binary(prec + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if !hasOp(condBinary[prec], op) {
+			return lhs, nil
+		}
+		p.pos++
+		rhs, err := p.binary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		lhs = binaryOp(op, lhs, rhs)
+	}
+}
+
+func hasOp(ops []string, op string) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func binaryOp(op string, l, r cond) cond {
+	switch op {
+	case "||":
+		return func(s *sfc, a access) int64 { return b2i(l(s, a) != 0 || r(s, a) != 0) }
+	case "&&":
+		return func(s *sfc, a access) int64 { return b2i(l(s, a) != 0 && r(s, a) != 0) }
+	case "==":
+		return func(s *sfc, a access) int64 { return b2i(l(s, a) == r(s, a)) }
+	case "!=":
+		return func(s *sfc, a access) int64 { return b2i(l(s, a) != r(s, a)) }
+	case "<=":
+		return func(s *sfc, a access) int64 { return b2i(l(s, a) <= r(s, a)) }
+	case ">=":
+		return func(s *sfc, a access) int64 { return b2i(l(s, a) >= r(s, a)) }
+	case "<":
+		return func(s *sfc, a access) int64 { return b2i(l(s, a) < r(s, a)) }
+	case ">":
+		return func(s *sfc, a access) int64 { return b2i(l(s, a) > r(s, a)) }
+	case "+":
+		return func(s *sfc, a access) int64 { return l(s, a) + r(s, a) }
+	case "-":
+		return func(s *sfc, a access) int64 { return l(s, a) - r(s, a) }
+	case "|":
+		return func(s *sfc, a access) int64 { return l(s, a) | r(s, a) }
+	case "^":
+		return func(s *sfc, a access) int64 { return l(s, a) ^ r(s, a) }
+	case "*":
+		return func(s *sfc, a access) int64 { return l(s, a) * r(s, a) }
+	case "/":
+		return func(s *sfc, a access) int64 {
+			if d := r(s, a); d != 0 {
+				return l(s, a) / d
+			}
+			return 0
+		}
+	case "%":
+		return func(s *sfc, a access) int64 {
+			if d := r(s, a); d != 0 {
+				return l(s, a) % d
+			}
+			return 0
+		}
+	case "<<":
+		return func(s *sfc, a access) int64 { return l(s, a) << uint64(r(s, a)&63) }
+	case ">>":
+		return func(s *sfc, a access) int64 { return l(s, a) >> uint64(r(s, a)&63) }
+	default: // "&"
+		return func(s *sfc, a access) int64 { return l(s, a) & r(s, a) }
+	}
+}
+
+func (p *condParser) unary() (cond, error) {
+	switch op := p.peek(); op {
+	case "!", "-", "^":
+		p.pos++
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "!":
+			return func(s *sfc, a access) int64 { return b2i(x(s, a) == 0) }, nil
+		case "-":
+			return func(s *sfc, a access) int64 { return -x(s, a) }, nil
+		}
+		return func(s *sfc, a access) int64 { return ^x(s, a) }, nil
+	}
+	return p.operand()
+}
+
+func (p *condParser) operand() (cond, error) {
+	tok := p.peek()
+	if tok == "" {
+		return nil, fmt.Errorf("condition %q: unexpected end", p.src)
+	}
+	p.pos++
+
+	switch {
+	case tok == "(":
+		x, err := p.binary(0)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("condition %q: missing ')'", p.src)
+		}
+		p.pos++
+		return x, nil
+
+	case tok[0] == '[':
+		s := strings.TrimSpace(tok[1 : len(tok)-1])
+		s = strings.ReplaceAll(strings.TrimPrefix(s, "$"), ":", "")
+		addr, err := strconv.ParseUint(s, 16, 24)
+		if err != nil {
+			return nil, fmt.Errorf("condition %q: invalid address %s", p.src, tok)
+		}
+		return func(s *sfc, _ access) int64 { return int64(s.peek(uint32(addr))) }, nil
+
+	case tok[0] == '$':
+		n, err := strconv.ParseUint(tok[1:], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("condition %q: invalid number %s", p.src, tok)
+		}
+		return constCond(int64(n)), nil
+
+	case tok[0] >= '0' && tok[0] <= '9':
+		n, err := strconv.ParseUint(tok, 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("condition %q: invalid number %s", p.src, tok)
+		}
+		return constCond(int64(n)), nil
+	}
+
+	if c, ok := condOperands[strings.ToUpper(tok)]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("condition %q: unknown operand %s", p.src, tok)
+}
+
+func constCond(n int64) cond {
+	return func(*sfc, access) int64 { return n }
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestParseCond(t *testing.T) {
+	s := New().(*sfc)
+	s.Pause(true)
+	copy(s.w.wram.buf[0x0010:], []uint8{0x05, 0x80})
+	r := &s.w.r
+	r.a, r.x, r.y = 0x1234, 0x0208, 0x0301
+	r.p.setPacked(0x24) // M=1 X=0 I=1
+	r.emulation = false
+	a := access{addr: 0x002118, value: 0x1F}
+
+	for _, tt := range []struct {
+		src  string
+		want int64
+	}{
+		// precedence and associativity
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 2 - 3", 5},
+		{"64 / 4 / 2", 8},
+		{"1 | 2 & 0", 1},
+		{"8 >> 1 + 1", 5},
+		{"1 << 2 * 2", 8}, // same level, left to right
+		{"6 ^ 3 == 5", 1},
+		{"1 + 1 == 2", 1},
+		{"2 < 3 == 1", 1},
+		{"0 || 1 && 0", 0},
+		{"1 || 0 && 0", 1},
+		{"1 == 1 && 0 == 1 || 1", 1},
+		{"7 % 4 + 1", 4},
+		{"1 / 0", 0},
+
+		// unary
+		{"-1 < 0", 1},
+		{"^0", -1},
+		{"!0", 1},
+		{"!5", 0},
+		{"- -3", 3},
+
+		// numbers
+		{"$1F", 31},
+		{"$1f", 31},
+		{"0x1F", 31},
+		{"31", 31},
+
+		// memory
+		{"[7E0010]", 0x05},
+		{"[$7E0011]", 0x80},
+		{"[7E:0010] + [7E0011]", 0x85},
+		{"[000010] == [7E0010]", 1},
+		{"[002100]", 0}, // I/O
+
+		// registers, M flag is set and X flag is clear
+		{"A", 0x34},
+		{"C", 0x1234},
+		{"X", 0x0208},
+		{"y", 0x0301},
+		{"P & $04 != 0", 1},
+		{"FM && !FX && FI", 1},
+		{"FE", 0},
+		{"VALUE == $1F && ADDR == $2118", 1},
+	} {
+		c, err := parseCond(tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if got := c(s, a); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseCondEmpty(t *testing.T) {
+	for _, src := range []string{"", "  "} {
+		c, err := parseCond(src)
+		if c != nil || err != nil {
+			t.Errorf("%q: condition should be nil (always true)", src)
+		}
+	}
+}
+
+func TestParseCondError(t *testing.T) {
+	for _, tt := range []struct {
+		src, want string
+	}{
+		{"A ==", `condition "A ==": unexpected end`},
+		{"(1", `condition "(1": missing ')'`},
+		{"[7E0010", `condition "[7E0010": missing ']'`},
+		{"[zz]", `condition "[zz]": invalid address [zz]`},
+		{"[1000000]", `condition "[1000000]": invalid address [1000000]`},
+		{"$zz", `condition "$zz": invalid number $zz`},
+		{"0x", `condition "0x": invalid number 0x`},
+		{"Q > 1", `condition "Q > 1": unknown operand Q`},
+		{"A @ 1", `condition "A @ 1": invalid character '@'`},
+		{"A == 1 1", `condition "A == 1 1": unexpected "1"`},
+		{"1 == )", `condition "1 == )": unknown operand )`},
+	} {
+		_, err := parseCond(tt.src)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.src, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package core
+
+import "fmt"
+
+// Registers of 65816
+type Registers struct {
+	A, X, Y, S, D uint16
+	DB            uint8
+	PC            uint32 // PB:PC
+	P             uint8  // NVMXDIZC
+	E             bool   // emulation mode
+}
+
+// Registers returns CPU registers at the current instruction boundary
+func (s *sfc) Registers() Registers {
+	r := &s.w.r
+	return Registers{
+		A: r.a, X: r.x, Y: r.y, S: r.s, D: r.d,
+		DB: r.db,
+		PC: r.pc.u32(),
+		P:  r.p.pack(),
+		E:  r.emulation,
+	}
+}
+
+// SetRegisters changes CPU registers while paused, E, M and X flags truncate registers as the CPU does
+func (s *sfc) SetRegisters(regs Registers) error {
+	if !s.pause || s.w.state != CPU_FETCH {
+		return fmt.Errorf("registers can be changed only while paused")
+	}
+	r := &s.w.r
+	r.a, r.x, r.y, r.s, r.d = regs.A, regs.X, regs.Y, regs.S, regs.D
+	r.db = regs.DB
+	r.pc = toU24(regs.PC)
+	r.p.setPacked(regs.P)
+	r.setEmulation(regs.E)
+	return nil
+}
+
+// ReadMemory reads the system bus without side effects (I/O registers read 0)
+func (s *sfc) ReadMemory(addr uint32, size int) []uint8 {
+	result := make([]uint8, size)
+	for i := range result {
+		result[i] = s.peek(addr + uint32(i))
+	}
+	return result
+}
+
+// WriteMemory writes data into the system bus while paused, I/O registers are written as CPU does (ROM is read only)
+func (s *sfc) WriteMemory(addr uint32, data []uint8) error {
+	if !s.pause {
+		return fmt.Errorf("memory can be written only while paused")
+	}
+	m := s.m
+	for i, val := range data {
+		a := (addr + uint32(i)) & 0xFF_FFFF
+		m.writer[m.lookup[a]](m.target[a], val)
+	}
+	return nil
+}
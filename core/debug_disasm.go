@@ -0,0 +1,36 @@
+package core
+
+import (
+	"github.com/pokemium/gsnes/core/disasm"
+)
+
+// Read memory without side effects (I/O registers read 0)
+func (s *sfc) peek(addr uint32) uint8 {
+	addr &= 0xFF_FFFF
+	bank, ofs := addr>>16, addr&0xFFFF
+	if bank&0x7F < 0x40 && ofs >= 0x2000 && ofs < 0x6000 {
+		return 0
+	}
+	m := s.m
+	return m.reader[m.lookup[addr]](m.target[addr], 0)
+}
+
+// Current CPU state for disassembler
+func (s *sfc) cpuState() disasm.State {
+	r := &s.w.r
+	return disasm.State{
+		P: r.p.pack(), E: r.emulation,
+		DB: r.db, D: r.d,
+		X: r.x, Y: r.y, S: r.s,
+		Live: true,
+	}
+}
+
+// Disassemble decodes n instructions from addr with current M/X flags, DB and D
+//
+// Effective addresses of indexed and indirect modes are resolved only for the instruction at PC.
+func (s *sfc) Disassemble(addr uint32, n int) []disasm.Instruction {
+	state := s.cpuState()
+	state.Live = addr == s.PC()
+	return disasm.Disassemble(s.peek, addr, n, state)
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Sprite is an OAM entry for debugger
+type Sprite struct {
+	Index         int // OBJ #N (0..127)
+	X, Y          int // X is -256..255
+	Width, Height int
+	Tile          int // 0..255 (tile number in the table)
+	Table2        bool
+	Palette       int // OBJ palette (0..7)
+	Priority      int // 0..3
+	HFlip, VFlip  bool
+	Large         bool
+}
+
+func (s *sfc) sprite(i int) Sprite {
+	oam := &s.ppu.oam
+	obj := &oam.objs[i]
+	x := int(obj.x)
+	if x >= 256 {
+		x -= 512
+	}
+	size := oam.size[btoi(obj.large)]
+	return Sprite{
+		Index: i, X: x, Y: int(obj.y),
+		Width: size, Height: size,
+		Tile: int(obj.tile), Table2: obj.table2,
+		Palette: int(obj.palID), Priority: int(obj.prio),
+		HFlip: obj.hflip, VFlip: obj.vflip,
+		Large: obj.large,
+	}
+}
+
+// Sprites returns all 128 sprites in OAM
+func (s *sfc) Sprites() []Sprite {
+	sprites := make([]Sprite, len(s.ppu.oam.objs))
+	for i := range sprites {
+		sprites[i] = s.sprite(i)
+	}
+	return sprites
+}
+
+// ScanlineSprites returns OBJ #N shown on the line(1..239) after range and time limits, from the highest priority one.
+func (s *sfc) ScanlineSprites(line int) []int {
+	if line < 1 || line > OVERSCAN {
+		return []int{}
+	}
+	l := &s.ppu.r.obj.shown[line-1]
+	result := make([]int, l.n)
+	for i := range result {
+		result[i] = int(l.objs[i])
+	}
+	return result
+}
+
+// SpriteImage renders a sprite as displayed (flips are applied, color 0 is transparent)
+func (s *sfc) SpriteImage(index int) (image.Image, error) {
+	if index < 0 || index >= len(s.ppu.oam.objs) {
+		return nil, fmt.Errorf("invalid OBJ: %d", index)
+	}
+
+	oam := &s.ppu.oam
+	obj := &oam.objs[index]
+	pal := s.ppu.pal.buf[0x80+int(obj.palID)*16:]
+	size := oam.size[btoi(obj.large)]
+
+	baseAddr := oam.tileDataAddr
+	if obj.table2 {
+		baseAddr += 0x1000 + oam.gap
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			row, col := uint16(y/8), uint16(x/8)
+			tileID := (uint16(obj.tile) + 16*row) & 0x00FF         // NOTE: OBJタイルの3桁目(Hex)は桁上がりしない
+			tileID = (tileID & 0xFFF0) | ((tileID + col) & 0x000F) // NOTE: OBJタイルの2,3桁目(Hex)は桁上がりしない
+			tileStart := (baseAddr + 16*uint(tileID)) & 0x7FFF
+
+			colorID := s.ppu.vram.pixel(TILE_4BPP, tileStart, x%8, y%8)
+			if colorID != 0 {
+				img.Set(flip(size, obj.hflip, x), flip(size, obj.vflip, y), pal[colorID].Color())
+			} else {
+				img.Set(flip(size, obj.hflip, x), flip(size, obj.vflip, y), color.Transparent)
+			}
+		}
+	}
+	return img, nil
+}
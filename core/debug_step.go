@@ -0,0 +1,63 @@
+package core
+
+import "fmt"
+
+// Resume the core and pause again when stop returns true on an opcode fetch
+//
+// The instruction at PC is always executed (exec breakpoint there is ignored), and breakpoint hits cancel it.
+func (s *sfc) runUntil(stop func(w *w65816) bool) {
+	first := true
+	b := &s.w.bkpts
+	b.skip = s.w.r.pc.u32()
+	b.stop = func(w *w65816) bool {
+		if first {
+			first = false
+			return false
+		}
+		return stop(w)
+	}
+	s.pause, b.hit = false, nil
+}
+
+// StepOver runs the instruction at PC, subroutine calls (JSR, JSL) and interrupt handlers are executed until they return
+func (s *sfc) StepOver() {
+	depth := len(s.callStack().frames)
+	s.runUntil(func(w *w65816) bool {
+		return len(w.calls.frames) <= depth
+	})
+}
+
+// StepOut runs until the current subroutine or interrupt handler returns
+func (s *sfc) StepOut() error {
+	depth := len(s.callStack().frames)
+	if depth == 0 {
+		return fmt.Errorf("call stack is empty")
+	}
+	s.runUntil(func(w *w65816) bool {
+		return len(w.calls.frames) < depth
+	})
+	return nil
+}
+
+// RunTo runs until PC reaches the address
+func (s *sfc) RunTo(addr uint32) {
+	addr &= 0xFF_FFFF
+	s.runUntil(func(w *w65816) bool {
+		return w.r.pc.u32() == addr
+	})
+}
+
+// RunToScanline runs until the first instruction on the line (V counter), the current line waits for the next frame
+func (s *sfc) RunToScanline(line int) error {
+	if line < 0 || line >= int(s.ppu.totalLines()) {
+		return fmt.Errorf("invalid scanline: %d", line)
+	}
+	prev := s.ppu.vcount
+	s.runUntil(func(w *w65816) bool {
+		v := w.c.ppu.vcount
+		reached := int(v) == line && prev != v
+		prev = v
+		return reached
+	})
+	return nil
+}
@@ -0,0 +1,243 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/pokemium/gsnes/core/coretest"
+)
+
+func newStepTest(t *testing.T, code []uint8, nmi uint16) *sfc {
+	t.Helper()
+	rom := coretest.LoROM(code)
+	coretest.SetVector(rom, 0xFFFA, nmi) // emulation mode NMI
+	s := New().(*sfc)
+	if err := s.LoadROM(rom); err != nil {
+		t.Fatal(err)
+	}
+	s.Pause(true)
+	return s
+}
+
+// Run frames until the core pauses
+func runToPause(t *testing.T, s *sfc) {
+	t.Helper()
+	for i := 0; i < 100 && !s.Paused(); i++ { // RunFrame may return before a frame ends
+		s.RunFrame()
+	}
+	if !s.Paused() {
+		t.Fatalf("core doesn't pause (PC: %06X)", s.PC())
+	}
+}
+
+func frameKinds(frames []Frame) []string {
+	kinds := []string{}
+	for _, f := range frames {
+		kinds = append(kinds, f.Kind)
+	}
+	return kinds
+}
+
+func checkFrames(t *testing.T, s *sfc, pc uint32, kinds ...string) {
+	t.Helper()
+	got := frameKinds(s.CallStack())
+	ok := s.PC() == pc && len(got) == len(kinds)
+	for i := 0; ok && i < len(kinds); i++ {
+		ok = got[i] == kinds[i]
+	}
+	if !ok {
+		t.Fatalf("PC %06X %v, want %06X %v", s.PC(), got, pc, kinds)
+	}
+}
+
+func TestCallStack(t *testing.T) {
+	code := make([]uint8, 0x30)
+	copy(code, []uint8{
+		0x78,             // 8000: SEI
+		0x20, 0x10, 0x80, // 8001: JSR $8010
+		0xA9, 0x01, // 8004: LDA #$01
+		0x80, 0xF9, // 8006: BRA $8001
+	})
+	copy(code[0x10:], []uint8{
+		0x22, 0x20, 0x80, 0x00, // 8010: JSL $008020
+		0x60, // 8014: RTS
+	})
+	copy(code[0x20:], []uint8{
+		0xEA, // 8020: NOP
+		0xEA, // 8021: NOP
+		0x6B, // 8022: RTL
+	})
+	s := newStepTest(t, code, 0)
+
+	if err := s.StepOut(); err == nil {
+		t.Fatal("StepOut with empty call stack")
+	}
+
+	for i := 0; i < 3; i++ {
+		s.RunInst()
+	}
+	checkFrames(t, s, 0x008020, "JSL", "JSR")
+	f := s.CallStack()
+	if f[0].Caller != 0x008010 || f[0].Entry != 0x008020 || f[0].Return != 0x008014 || f[1].Caller != 0x008001 || f[1].Return != 0x008004 {
+		t.Fatalf("%v", f)
+	}
+
+	if err := s.StepOut(); err != nil {
+		t.Fatal(err)
+	}
+	runToPause(t, s)
+	checkFrames(t, s, 0x008014, "JSR")
+
+	s.StepOut()
+	runToPause(t, s)
+	checkFrames(t, s, 0x008004)
+
+	// JSR is stepped over
+	s.StepOver()
+	runToPause(t, s)
+	checkFrames(t, s, 0x008006)
+	s.StepOver()
+	runToPause(t, s)
+	checkFrames(t, s, 0x008001)
+	s.StepOver()
+	runToPause(t, s)
+	checkFrames(t, s, 0x008004)
+
+	s.RunTo(0x008021)
+	runToPause(t, s)
+	checkFrames(t, s, 0x008021, "JSL", "JSR")
+}
+
+// Frames are dropped when SP goes above them, e.g. return address is discarded by PLA
+func TestCallStackDrop(t *testing.T) {
+	code := make([]uint8, 0x40)
+	copy(code, []uint8{
+		0x78,             // 8000: SEI
+		0x20, 0x10, 0x80, // 8001: JSR $8010
+	})
+	copy(code[0x10:], []uint8{
+		0x68,             // 8010: PLA
+		0x68,             // 8011: PLA
+		0x20, 0x30, 0x80, // 8012: JSR $8030
+		0x80, 0xFE, // 8015: BRA $8015
+	})
+	copy(code[0x30:], []uint8{
+		0xEA, // 8030: NOP
+		0x60, // 8031: RTS
+	})
+	s := newStepTest(t, code, 0)
+
+	s.RunTo(0x008012)
+	runToPause(t, s)
+	checkFrames(t, s, 0x008012, "JSR") // SP is above the frame, but it is dropped by the next call
+
+	s.RunInst()
+	checkFrames(t, s, 0x008030, "JSR")
+	if f := s.CallStack()[0]; f.Caller != 0x008012 {
+		t.Fatalf("%v", f)
+	}
+
+	s.StepOut()
+	runToPause(t, s)
+	checkFrames(t, s, 0x008015)
+}
+
+// NMI handler (which calls a subroutine) is taken while a subroutine waits for it
+func TestStepOverNMI(t *testing.T) {
+	code := make([]uint8, 0x80)
+	copy(code, []uint8{
+		0x78,       // 8000: SEI
+		0xA9, 0x80, // 8001: LDA #$80
+		0x8D, 0x00, 0x42, // 8003: STA $4200 (NMI enable)
+		0x20, 0x40, 0x80, // 8006: JSR $8040
+		0x80, 0xFB, // 8009: BRA $8006
+	})
+	copy(code[0x40:], []uint8{
+		0xA5, 0x10, // 8040: LDA $10
+		0xF0, 0xFC, // 8042: BEQ $8040
+		0x64, 0x10, // 8044: STZ $10
+		0x60, // 8046: RTS
+	})
+	copy(code[0x60:], []uint8{
+		0xE6, 0x10, // 8060: INC $10
+		0xE6, 0x11, // 8062: INC $11
+		0x20, 0x70, 0x80, // 8064: JSR $8070
+		0x40, // 8067: RTI
+	})
+	copy(code[0x70:], []uint8{
+		0xEA, // 8070: NOP
+		0x60, // 8071: RTS
+	})
+	s := newStepTest(t, code, 0x8060)
+
+	s.RunTo(0x008006)
+	runToPause(t, s)
+
+	// JSR $8040 returns after NMI, the handler doesn't stop StepOver
+	s.StepOver()
+	runToPause(t, s)
+	checkFrames(t, s, 0x008009)
+	if n := s.w.wram.buf[0x0011]; n != 1 {
+		t.Fatalf("NMI is taken %d times", n)
+	}
+
+	// step out from the subroutine in the handler, and from the handler
+	s.StepOver()
+	runToPause(t, s)
+	s.RunTo(0x008070)
+	runToPause(t, s)
+	checkFrames(t, s, 0x008070, "JSR", "NMI", "JSR")
+	nmi := s.CallStack()[1]
+	if nmi.Entry != 0x008060 || nmi.Caller != nmi.Return || nmi.Return < 0x008040 || nmi.Return > 0x008042 {
+		t.Fatalf("%v", nmi)
+	}
+
+	s.StepOut()
+	runToPause(t, s)
+	checkFrames(t, s, 0x008067, "NMI", "JSR")
+	s.StepOut()
+	runToPause(t, s)
+	checkFrames(t, s, nmi.Return, "JSR")
+}
+
+func TestRunToScanline(t *testing.T) {
+	code := []uint8{
+		0x78,       // 8000: SEI
+		0xEA,       // 8001: NOP
+		0x80, 0xFD, // 8002: BRA $8001
+	}
+	s := newStepTest(t, code, 0)
+	s.Pause(false)
+	for i := 0; i < 3; i++ {
+		s.RunFrame()
+	}
+	s.Pause(true)
+
+	if err := s.RunToScanline(-1); err == nil {
+		t.Error("line -1 is accepted")
+	}
+	if err := s.RunToScanline(int(s.ppu.totalLines())); err == nil {
+		t.Error("line after the last line is accepted")
+	}
+
+	// later line in the same frame
+	line, frame := int(s.ppu.vcount)+10, s.frame
+	s.RunToScanline(line)
+	runToPause(t, s)
+	if int(s.ppu.vcount) != line || s.frame != frame {
+		t.Fatalf("line %d frame %d, want line %d frame %d", s.ppu.vcount, s.frame, line, frame)
+	}
+
+	// the current line waits for the next frame
+	s.RunToScanline(line)
+	runToPause(t, s)
+	if int(s.ppu.vcount) != line || s.frame != frame+1 {
+		t.Fatalf("line %d frame %d, want line %d frame %d", s.ppu.vcount, s.frame, line, frame+1)
+	}
+
+	// earlier line wraps to the next frame
+	s.RunToScanline(0)
+	runToPause(t, s)
+	if s.ppu.vcount != 0 || s.frame != frame+2 {
+		t.Fatalf("line %d frame %d, want line 0 frame %d", s.ppu.vcount, s.frame, frame+2)
+	}
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pokemium/gsnes/core/disasm"
+)
+
+// Trace log formats
+const (
+	TRACE_BSNES = "bsnes" // 008000 sei    A:0000 X:0000 Y:0000 S:01ff D:0000 B:00 nvMXdIzc V:  0 H: 744 C:...
+	TRACE_MESEN = "mesen" // 00:8000  SEI    A:0000 X:0000 Y:0000 S:01FF D:0000 DB:00 P:34 V:0   H:186 Cycle:...
+)
+
+// AddrRange is an inclusive range of 24bit address
+type AddrRange struct {
+	Start, End uint32
+}
+
+func (r AddrRange) contains(addr uint32) bool {
+	return addr >= r.Start && addr <= r.End
+}
+
+// Instruction trace logger
+type tracer struct {
+	f      *os.File
+	w      *bufio.Writer
+	format string
+	ranges []AddrRange // PC filter (empty: all)
+}
+
+// StartTrace writes each executed instruction into the file until StopTrace, only instructions in ranges are logged if any
+func (s *sfc) StartTrace(path, format string, ranges ...AddrRange) error {
+	format = strings.ToLower(format)
+	if format != TRACE_BSNES && format != TRACE_MESEN {
+		return fmt.Errorf("invalid trace format: %s", format)
+	}
+	if err := s.StopTrace(); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	s.trace = &tracer{
+		f:      f,
+		w:      bufio.NewWriterSize(f, int(MB)),
+		format: format,
+		ranges: ranges,
+	}
+	return nil
+}
+
+// StopTrace flushes and closes the trace log
+func (s *sfc) StopTrace() error {
+	t := s.trace
+	if t == nil {
+		return nil
+	}
+	s.trace = nil
+
+	if err := t.w.Flush(); err != nil {
+		t.f.Close()
+		return err
+	}
+	return t.f.Close()
+}
+
+// Log the instruction at PC (called before it's executed)
+func (t *tracer) log(s *sfc) {
+	pc := s.w.r.pc.u32()
+	if len(t.ranges) > 0 {
+		found := false
+		for _, r := range t.ranges {
+			if r.contains(pc) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return
+		}
+	}
+
+	state := s.cpuState()
+	inst := disasm.Decode(s.peek, pc, state)
+	r := &s.w.r
+	v, h, cycle := s.ppu.vcount, s.ppu.hcount, s.s.Cycle()
+
+	switch t.format {
+	case TRACE_BSNES:
+		text := strings.ToLower(inst.String())
+		if inst.Resolved && inst.Mode != disasm.RELATIVE && inst.Mode != disasm.RELATIVE_LONG {
+			text = fmt.Sprintf("%-20s[%06x]", text, inst.Effective)
+		}
+		fmt.Fprintf(t.w, "%06x %-28s A:%04x X:%04x Y:%04x S:%04x D:%04x B:%02x %s V:%3d H:%4d C:%d\n",
+			pc, text, r.a, r.x, r.y, r.s, r.d, r.db, flags(state.P), v, h*4, cycle)
+
+	case TRACE_MESEN:
+		fmt.Fprintf(t.w, "%02X:%04X  %-28s A:%04X X:%04X Y:%04X S:%04X D:%04X DB:%02X P:%02X V:%-3d H:%-3d Cycle:%d\n",
+			pc>>16, uint16(pc), inst.String(), r.a, r.x, r.y, r.s, r.d, r.db, state.P, v, h, cycle)
+	}
+}
+
+// NVMXDIZC (upper case is set)
+func flags(p uint8) string {
+	const names = "NVMXDIZC"
+	result := []byte(strings.ToLower(names))
+	for i := range result {
+		if bit(p, 7-i) {
+			result[i] = names[i]
+		}
+	}
+	return string(result)
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/pokemium/iro"
+)
+
+// Tile formats for tile viewer
+const (
+	TILE_2BPP  = "2BPP"
+	TILE_4BPP  = "4BPP"
+	TILE_8BPP  = "8BPP"
+	TILE_MODE7 = "MODE7" // 8bpp, pixel is upper byte of VRAM word
+)
+
+const TILESHEET_COLUMNS = 16 // tiles per row in tile sheet
+
+// bytes per tile in VRAM (Mode7 tile uses only upper bytes of 64 words)
+func tileBytes(format string) int {
+	switch format {
+	case TILE_2BPP:
+		return 16
+	case TILE_4BPP:
+		return 32
+	case TILE_8BPP:
+		return 64
+	case TILE_MODE7:
+		return 128
+	}
+	return 0
+}
+
+// Bits per pixel of the tile format
+func tileBpp(format string) int {
+	switch format {
+	case TILE_2BPP:
+		return 2
+	case TILE_4BPP:
+		return 4
+	case TILE_8BPP, TILE_MODE7:
+		return 8
+	}
+	return 0
+}
+
+// Tiles renders VRAM[addr:addr+size] as a tile sheet
+//
+// addr and size are in bytes (size <= 0 means till the end of VRAM).
+// palette is a CGRAM palette index (0..63 in 2bpp, 0..15 in 4bpp), 8bpp and Mode7 use the whole CGRAM.
+func (s *sfc) Tiles(format string, addr, size, palette int) (image.Image, error) {
+	format = strings.ToUpper(format)
+	bpp := tileBpp(format)
+	if bpp == 0 {
+		return nil, fmt.Errorf("invalid tile format: %s", format)
+	}
+	if addr < 0 || addr >= int(VRAM_SIZE) {
+		return nil, fmt.Errorf("invalid VRAM address: 0x%04X", addr)
+	}
+	if size <= 0 || addr+size > int(VRAM_SIZE) {
+		size = int(VRAM_SIZE) - addr
+	}
+
+	colors := 256
+	if format == TILE_2BPP || format == TILE_4BPP {
+		colors = 1 << bpp
+	}
+	if palette < 0 || (palette+1)*colors > PAL_SIZE/2 {
+		return nil, fmt.Errorf("invalid palette for %s: %d", format, palette)
+	}
+	pal := s.ppu.pal.buf[palette*colors : (palette+1)*colors]
+
+	n := (size + tileBytes(format) - 1) / tileBytes(format)
+	w, h := TILESHEET_COLUMNS*8, ((n+TILESHEET_COLUMNS-1)/TILESHEET_COLUMNS)*8
+	buf := make([]iro.RGB555, w*h)
+	for i := 0; i < n; i++ {
+		ofs := uint(addr/2 + i*tileBytes(format)/2)
+		tx, ty := (i%TILESHEET_COLUMNS)*8, (i/TILESHEET_COLUMNS)*8
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				buf[(ty+y)*w+tx+x] = pal[s.ppu.vram.pixel(format, ofs, x, y)]
+			}
+		}
+	}
+	return iro.RGB555ToImage(buf, w, h, nil), nil
+}
+
+// VideoWrites returns how many times VRAM and CGRAM are written
+//
+// Writes into MMap-ed buffers from outside the emulator are not counted.
+func (s *sfc) VideoWrites() (vram, cgram uint64) {
+	return s.ppu.vram.writes, s.ppu.pal.writes
+}
+
+// Color index of pixel (x, y) in the tile at VRAM[ofs] (unit is word)
+func (v *vram) pixel(format string, ofs uint, x, y int) uint8 {
+	if format == TILE_MODE7 {
+		return uint8(v.buf[(ofs+uint(8*y+x))&0x7FFF] >> 8)
+	}
+
+	// 2 bitplanes are interleaved in each word, 8 words for each pair of bitplanes
+	c := uint8(0)
+	for k := 0; k < tileBpp(format)/2; k++ {
+		row := v.buf[(ofs+uint(8*k+y))&0x7FFF]
+		c |= uint8((row>>(7-x))&1) << (2 * k)
+		c |= uint8((row>>(15-x))&1) << (2*k + 1)
+	}
+	return c
+}
@@ -0,0 +1,260 @@
+// Package disasm is a 65816 disassembler
+//
+// It works on any memory which can be read byte by byte, so it can be used for live CPU and raw ROM image.
+package disasm
+
+import (
+	"fmt"
+)
+
+// Memory reads a byte at 24bit address (it shouldn't have side effects)
+type Memory func(addr uint32) uint8
+
+// State is CPU state used for decoding
+//
+// Immediate width is decided by P and E, direct and absolute addresses are resolved by D and DB.
+// Indexed and indirect addresses need actual registers, so they are resolved only if Live is true.
+type State struct {
+	P  uint8 // processor status (C: bit0, X: bit4, M: bit5)
+	E  bool  // emulation mode (M and X are always 1)
+	DB uint8
+	D  uint16
+
+	X, Y, S uint16
+	Live    bool // X, Y, S and memory hold actual values at the instruction
+}
+
+// 8bit accumulator
+func (s State) M8() bool {
+	return s.E || s.P&0x20 != 0
+}
+
+// 8bit index registers
+func (s State) X8() bool {
+	return s.E || s.P&0x10 != 0
+}
+
+// Track updates M/X flags after the instruction, they are changed by REP, SEP and XCE (with CLC/SEC before it)
+//
+// Registers may change after the instruction, so the state isn't Live anymore.
+func (s *State) Track(inst Instruction) {
+	switch inst.Name {
+	case "REP":
+		s.P &^= uint8(inst.Operand)
+	case "SEP":
+		s.P |= uint8(inst.Operand)
+	case "CLC":
+		s.P &^= 0x01
+	case "SEC":
+		s.P |= 0x01
+	case "XCE":
+		c := s.P&0x01 != 0
+		if s.E {
+			s.P |= 0x01
+		} else {
+			s.P &^= 0x01
+		}
+		s.E = c
+	}
+	if s.E {
+		s.P |= 0x30
+	}
+	s.Live = false
+}
+
+// Instruction is a decoded instruction
+type Instruction struct {
+	Addr    uint32  // PB:PC
+	Bytes   []uint8 // opcode and operand
+	Name    string  // mnemonic
+	Mode    Mode
+	Operand uint32 // little endian operand (relative branches have target address)
+
+	Effective uint32 // effective address (memory operand or jump target)
+	Resolved  bool   // Effective is valid
+}
+
+func (i Instruction) Size() int {
+	return len(i.Bytes)
+}
+
+func (i Instruction) String() string {
+	op := i.Operand
+	switch i.Mode {
+	case IMPLIED:
+		return i.Name
+	case ACCUMULATOR:
+		return i.Name + " A"
+	case IMMEDIATE_M, IMMEDIATE_X, IMMEDIATE8:
+		if i.Size() == 3 {
+			return fmt.Sprintf("%s #$%04X", i.Name, op)
+		}
+		return fmt.Sprintf("%s #$%02X", i.Name, op)
+	case DIRECT:
+		return fmt.Sprintf("%s $%02X", i.Name, op)
+	case DIRECT_X:
+		return fmt.Sprintf("%s $%02X,X", i.Name, op)
+	case DIRECT_Y:
+		return fmt.Sprintf("%s $%02X,Y", i.Name, op)
+	case DIRECT_INDIRECT:
+		return fmt.Sprintf("%s ($%02X)", i.Name, op)
+	case DIRECT_INDIRECT_X:
+		return fmt.Sprintf("%s ($%02X,X)", i.Name, op)
+	case DIRECT_INDIRECT_Y:
+		return fmt.Sprintf("%s ($%02X),Y", i.Name, op)
+	case DIRECT_INDIRECT_LONG:
+		return fmt.Sprintf("%s [$%02X]", i.Name, op)
+	case DIRECT_INDIRECT_LONG_Y:
+		return fmt.Sprintf("%s [$%02X],Y", i.Name, op)
+	case ABSOLUTE:
+		return fmt.Sprintf("%s $%04X", i.Name, op)
+	case ABSOLUTE_X:
+		return fmt.Sprintf("%s $%04X,X", i.Name, op)
+	case ABSOLUTE_Y:
+		return fmt.Sprintf("%s $%04X,Y", i.Name, op)
+	case ABSOLUTE_LONG:
+		return fmt.Sprintf("%s $%06X", i.Name, op)
+	case ABSOLUTE_LONG_X:
+		return fmt.Sprintf("%s $%06X,X", i.Name, op)
+	case ABSOLUTE_INDIRECT:
+		return fmt.Sprintf("%s ($%04X)", i.Name, op)
+	case ABSOLUTE_INDIRECT_X:
+		return fmt.Sprintf("%s ($%04X,X)", i.Name, op)
+	case ABSOLUTE_INDIRECT_LONG:
+		return fmt.Sprintf("%s [$%04X]", i.Name, op)
+	case STACK_RELATIVE:
+		return fmt.Sprintf("%s $%02X,S", i.Name, op)
+	case STACK_RELATIVE_INDIRECT_Y:
+		return fmt.Sprintf("%s ($%02X,S),Y", i.Name, op)
+	case RELATIVE, RELATIVE_LONG:
+		return fmt.Sprintf("%s $%04X", i.Name, uint16(op))
+	case BLOCK_MOVE:
+		return fmt.Sprintf("%s $%02X,$%02X", i.Name, op>>8, op&0xFF) // operand bytes are dest, src
+	}
+	return i.Name
+}
+
+// Operand size of each addressing mode (immediate M/X is 1 in 8bit mode)
+var operandSize = [...]int{
+	IMPLIED: 0, ACCUMULATOR: 0, IMMEDIATE_M: 2, IMMEDIATE_X: 2, IMMEDIATE8: 1,
+	DIRECT: 1, DIRECT_X: 1, DIRECT_Y: 1, DIRECT_INDIRECT: 1, DIRECT_INDIRECT_X: 1, DIRECT_INDIRECT_Y: 1,
+	DIRECT_INDIRECT_LONG: 1, DIRECT_INDIRECT_LONG_Y: 1,
+	ABSOLUTE: 2, ABSOLUTE_X: 2, ABSOLUTE_Y: 2, ABSOLUTE_LONG: 3, ABSOLUTE_LONG_X: 3,
+	ABSOLUTE_INDIRECT: 2, ABSOLUTE_INDIRECT_X: 2, ABSOLUTE_INDIRECT_LONG: 2,
+	STACK_RELATIVE: 1, STACK_RELATIVE_INDIRECT_Y: 1,
+	RELATIVE: 1, RELATIVE_LONG: 2, BLOCK_MOVE: 2,
+}
+
+// Decode disassembles an instruction at addr
+func Decode(mem Memory, addr uint32, s State) Instruction {
+	pb, pc := addr&0xFF_0000, uint16(addr)
+	op := opcodes[mem(addr)]
+
+	size := operandSize[op.mode]
+	switch {
+	case op.mode == IMMEDIATE_M && s.M8(), op.mode == IMMEDIATE_X && s.X8():
+		size = 1
+	}
+
+	inst := Instruction{
+		Addr:  addr,
+		Bytes: make([]uint8, size+1),
+		Name:  op.name,
+		Mode:  op.mode,
+	}
+	for i := range inst.Bytes {
+		inst.Bytes[i] = mem(pb | uint32(pc+uint16(i))) // PC wraps in the bank
+		if i > 0 {
+			inst.Operand |= uint32(inst.Bytes[i]) << (8 * (i - 1))
+		}
+	}
+
+	next := pc + uint16(size+1)
+	switch op.mode {
+	case RELATIVE:
+		inst.Operand = pb | uint32(next+uint16(int8(inst.Operand)))
+	case RELATIVE_LONG:
+		inst.Operand = pb | uint32(next+uint16(inst.Operand))
+	}
+
+	inst.Effective, inst.Resolved = effective(mem, inst, s)
+	return inst
+}
+
+func effective(mem Memory, inst Instruction, s State) (uint32, bool) {
+	pb, op := inst.Addr&0xFF_0000, inst.Operand
+	db := uint32(s.DB) << 16
+	dp := func(ofs uint16) uint32 { return uint32(s.D + uint16(op) + ofs) } // direct page is in bank 0
+	read16 := func(addr uint32) uint32 {
+		return uint32(mem(addr)) | uint32(mem((addr&0xFF_0000)|uint32(uint16(addr+1))))<<8
+	}
+	read24 := func(addr uint32) uint32 {
+		return read16(addr) | uint32(mem((addr&0xFF_0000)|uint32(uint16(addr+2))))<<16
+	}
+	add := func(addr uint32, ofs uint16) uint32 { return (addr + uint32(ofs)) & 0xFF_FFFF }
+
+	switch inst.Mode {
+	case DIRECT:
+		return dp(0), true
+	case ABSOLUTE:
+		switch inst.Name {
+		case "JMP", "JSR":
+			return pb | op, true
+		case "PEA":
+			return 0, false
+		}
+		return db | op, true
+	case ABSOLUTE_LONG, RELATIVE, RELATIVE_LONG:
+		return op, true
+	}
+
+	if !s.Live {
+		return 0, false
+	}
+
+	switch inst.Mode {
+	case DIRECT_X:
+		return dp(s.X), true
+	case DIRECT_Y:
+		return dp(s.Y), true
+	case DIRECT_INDIRECT:
+		return db | read16(dp(0)), true
+	case DIRECT_INDIRECT_X:
+		return db | read16(dp(s.X)), true
+	case DIRECT_INDIRECT_Y:
+		return add(db|read16(dp(0)), s.Y), true
+	case DIRECT_INDIRECT_LONG:
+		return read24(dp(0)), true
+	case DIRECT_INDIRECT_LONG_Y:
+		return add(read24(dp(0)), s.Y), true
+	case ABSOLUTE_X:
+		return add(db|op, s.X), true
+	case ABSOLUTE_Y:
+		return add(db|op, s.Y), true
+	case ABSOLUTE_LONG_X:
+		return add(op, s.X), true
+	case ABSOLUTE_INDIRECT:
+		return pb | read16(op), true
+	case ABSOLUTE_INDIRECT_X:
+		return pb | read16(pb|uint32(uint16(op)+s.X)), true
+	case ABSOLUTE_INDIRECT_LONG:
+		return read24(op), true
+	case STACK_RELATIVE:
+		return uint32(s.S + uint16(op)), true
+	case STACK_RELATIVE_INDIRECT_Y:
+		return add(db|read16(uint32(s.S+uint16(op))), s.Y), true
+	}
+	return 0, false
+}
+
+// Disassemble decodes n instructions from addr linearly, M/X flags are tracked through them
+func Disassemble(mem Memory, addr uint32, n int, s State) []Instruction {
+	result := make([]Instruction, 0, n)
+	for i := 0; i < n; i++ {
+		inst := Decode(mem, addr, s)
+		result = append(result, inst)
+		s.Track(inst)
+		addr = (addr & 0xFF_0000) | uint32(uint16(addr)+uint16(inst.Size()))
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package disasm
+
+import (
+	"testing"
+)
+
+// Memory with code at 00:8000
+func testMemory(code ...uint8) Memory {
+	return func(addr uint32) uint8 {
+		if addr >= 0x8000 && addr < 0x8000+uint32(len(code)) {
+			return code[addr-0x8000]
+		}
+		return 0
+	}
+}
+
+func TestImmediateWidth(t *testing.T) {
+	mem := testMemory(
+		0x18,       // CLC
+		0xFB,       // XCE
+		0xC2, 0x30, // REP #$30
+		0xA9, 0x34, 0x12, // LDA #$1234
+		0xA2, 0xFF, 0x01, // LDX #$01FF
+		0xE2, 0x20, // SEP #$20
+		0xA9, 0x12, // LDA #$12
+		0xA0, 0x34, 0x12, // LDY #$1234
+		0xE2, 0x10, // SEP #$10
+		0xC0, 0x12, // CPY #$12
+		0x38,       // SEC
+		0xFB,       // XCE
+		0xC2, 0x30, // REP #$30 (ignored in emulation mode)
+		0x69, 0x12, // ADC #$12
+	)
+
+	want := []string{
+		"CLC", "XCE", "REP #$30", "LDA #$1234", "LDX #$01FF", "SEP #$20", "LDA #$12", "LDY #$1234",
+		"SEP #$10", "CPY #$12", "SEC", "XCE", "REP #$30", "ADC #$12",
+	}
+	insts := Disassemble(mem, 0x8000, len(want), State{P: 0x34, E: true})
+	for i, inst := range insts {
+		if inst.String() != want[i] {
+			t.Errorf("%06X: got %q, want %q", inst.Addr, inst, want[i])
+		}
+	}
+}
+
+func TestBlockMove(t *testing.T) {
+	// operand bytes are dest, src
+	for _, tt := range []struct {
+		code []uint8
+		want string
+	}{
+		{[]uint8{0x54, 0x7E, 0x00}, "MVN $00,$7E"},
+		{[]uint8{0x44, 0x00, 0x7F}, "MVP $7F,$00"},
+	} {
+		inst := Decode(testMemory(tt.code...), 0x8000, State{})
+		if inst.String() != tt.want || inst.Size() != 3 {
+			t.Errorf("% X: got %q (%d bytes), want %q", tt.code, inst, inst.Size(), tt.want)
+		}
+	}
+}
+
+func TestEffective(t *testing.T) {
+	mem := func(addr uint32) uint8 {
+		switch addr {
+		case 0x000110, 0x000114: // pointer at D+$10 (and D+$10+X)
+			return 0x34
+		case 0x000111, 0x000115:
+			return 0x12
+		case 0x000112, 0x000116:
+			return 0x7F
+		}
+		return 0
+	}
+	s := State{DB: 0x7E, D: 0x0100, X: 4, Y: 2, S: 0x1F0, Live: true}
+
+	for _, tt := range []struct {
+		code     []uint8
+		live     bool
+		want     uint32
+		resolved bool
+	}{
+		{[]uint8{0xA5, 0x10}, false, 0x000110, true},             // LDA $10 (D, bank 0)
+		{[]uint8{0xAD, 0x00, 0x21}, false, 0x7E2100, true},       // LDA $2100 (DB)
+		{[]uint8{0xAF, 0x00, 0x21, 0x00}, false, 0x002100, true}, // LDA $002100
+		{[]uint8{0x4C, 0x00, 0x90}, false, 0x009000, true},       // JMP $9000 (PB)
+		{[]uint8{0xB5, 0x10}, false, 0, false},                   // LDA $10,X needs X
+		{[]uint8{0xB5, 0x10}, true, 0x000114, true},              // LDA $10,X
+		{[]uint8{0xBD, 0x00, 0x21}, true, 0x7E2104, true},        // LDA $2100,X
+		{[]uint8{0xB2, 0x10}, true, 0x7E1234, true},              // LDA ($10)
+		{[]uint8{0xA1, 0x10}, true, 0x7E1234, true},              // LDA ($10,X)
+		{[]uint8{0xB1, 0x10}, true, 0x7E1236, true},              // LDA ($10),Y
+		{[]uint8{0xA7, 0x10}, true, 0x7F1234, true},              // LDA [$10]
+		{[]uint8{0xB7, 0x10}, true, 0x7F1236, true},              // LDA [$10],Y
+		{[]uint8{0xA3, 0x03}, true, 0x0001F3, true},              // LDA $03,S
+	} {
+		code := tt.code
+		st := s
+		st.Live = tt.live
+		inst := Decode(func(addr uint32) uint8 {
+			if addr >= 0x8000 && addr < 0x8000+uint32(len(code)) {
+				return code[addr-0x8000]
+			}
+			return mem(addr)
+		}, 0x8000, st)
+		if inst.Resolved != tt.resolved || inst.Effective != tt.want {
+			t.Errorf("%s (live: %v): got %06X (%v), want %06X (%v)", inst, tt.live, inst.Effective, inst.Resolved, tt.want, tt.resolved)
+		}
+	}
+}
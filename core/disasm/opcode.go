@@ -0,0 +1,122 @@
+package disasm
+
+// Mode is addressing mode of 65816 instruction
+type Mode int
+
+const (
+	IMPLIED                   Mode = iota // NOP
+	ACCUMULATOR                           // ASL A
+	IMMEDIATE_M                           // LDA #$12 or #$1234 (by M flag)
+	IMMEDIATE_X                           // LDX #$12 or #$1234 (by X flag)
+	IMMEDIATE8                            // REP #$30
+	DIRECT                                // LDA $12
+	DIRECT_X                              // LDA $12,X
+	DIRECT_Y                              // LDX $12,Y
+	DIRECT_INDIRECT                       // LDA ($12)
+	DIRECT_INDIRECT_X                     // LDA ($12,X)
+	DIRECT_INDIRECT_Y                     // LDA ($12),Y
+	DIRECT_INDIRECT_LONG                  // LDA [$12]
+	DIRECT_INDIRECT_LONG_Y                // LDA [$12],Y
+	ABSOLUTE                              // LDA $1234
+	ABSOLUTE_X                            // LDA $1234,X
+	ABSOLUTE_Y                            // LDA $1234,Y
+	ABSOLUTE_LONG                         // LDA $123456
+	ABSOLUTE_LONG_X                       // LDA $123456,X
+	ABSOLUTE_INDIRECT                     // JMP ($1234)
+	ABSOLUTE_INDIRECT_X                   // JMP ($1234,X)
+	ABSOLUTE_INDIRECT_LONG                // JML [$1234]
+	STACK_RELATIVE                        // LDA $12,S
+	STACK_RELATIVE_INDIRECT_Y             // LDA ($12,S),Y
+	RELATIVE                              // BRA $8012
+	RELATIVE_LONG                         // BRL $8012
+	BLOCK_MOVE                            // MVN $7E,$00 (src, dest)
+)
+
+type opcode struct {
+	name string
+	mode Mode
+}
+
+// Same order as opTable in core/cpu_optable.go (instruction sizes are checked against it by TestOpTableSize in core)
+var opcodes = [256]opcode{
+	// 0x00
+	{"BRK", IMMEDIATE8}, {"ORA", DIRECT_INDIRECT_X}, {"COP", IMMEDIATE8}, {"ORA", STACK_RELATIVE},
+	{"TSB", DIRECT}, {"ORA", DIRECT}, {"ASL", DIRECT}, {"ORA", DIRECT_INDIRECT_LONG},
+	{"PHP", IMPLIED}, {"ORA", IMMEDIATE_M}, {"ASL", ACCUMULATOR}, {"PHD", IMPLIED},
+	{"TSB", ABSOLUTE}, {"ORA", ABSOLUTE}, {"ASL", ABSOLUTE}, {"ORA", ABSOLUTE_LONG},
+	// 0x10
+	{"BPL", RELATIVE}, {"ORA", DIRECT_INDIRECT_Y}, {"ORA", DIRECT_INDIRECT}, {"ORA", STACK_RELATIVE_INDIRECT_Y},
+	{"TRB", DIRECT}, {"ORA", DIRECT_X}, {"ASL", DIRECT_X}, {"ORA", DIRECT_INDIRECT_LONG_Y},
+	{"CLC", IMPLIED}, {"ORA", ABSOLUTE_Y}, {"INC", ACCUMULATOR}, {"TCS", IMPLIED},
+	{"TRB", ABSOLUTE}, {"ORA", ABSOLUTE_X}, {"ASL", ABSOLUTE_X}, {"ORA", ABSOLUTE_LONG_X},
+	// 0x20
+	{"JSR", ABSOLUTE}, {"AND", DIRECT_INDIRECT_X}, {"JSL", ABSOLUTE_LONG}, {"AND", STACK_RELATIVE},
+	{"BIT", DIRECT}, {"AND", DIRECT}, {"ROL", DIRECT}, {"AND", DIRECT_INDIRECT_LONG},
+	{"PLP", IMPLIED}, {"AND", IMMEDIATE_M}, {"ROL", ACCUMULATOR}, {"PLD", IMPLIED},
+	{"BIT", ABSOLUTE}, {"AND", ABSOLUTE}, {"ROL", ABSOLUTE}, {"AND", ABSOLUTE_LONG},
+	// 0x30
+	{"BMI", RELATIVE}, {"AND", DIRECT_INDIRECT_Y}, {"AND", DIRECT_INDIRECT}, {"AND", STACK_RELATIVE_INDIRECT_Y},
+	{"BIT", DIRECT_X}, {"AND", DIRECT_X}, {"ROL", DIRECT_X}, {"AND", DIRECT_INDIRECT_LONG_Y},
+	{"SEC", IMPLIED}, {"AND", ABSOLUTE_Y}, {"DEC", ACCUMULATOR}, {"TSC", IMPLIED},
+	{"BIT", ABSOLUTE_X}, {"AND", ABSOLUTE_X}, {"ROL", ABSOLUTE_X}, {"AND", ABSOLUTE_LONG_X},
+	// 0x40
+	{"RTI", IMPLIED}, {"EOR", DIRECT_INDIRECT_X}, {"WDM", IMMEDIATE8}, {"EOR", STACK_RELATIVE},
+	{"MVP", BLOCK_MOVE}, {"EOR", DIRECT}, {"LSR", DIRECT}, {"EOR", DIRECT_INDIRECT_LONG},
+	{"PHA", IMPLIED}, {"EOR", IMMEDIATE_M}, {"LSR", ACCUMULATOR}, {"PHK", IMPLIED},
+	{"JMP", ABSOLUTE}, {"EOR", ABSOLUTE}, {"LSR", ABSOLUTE}, {"EOR", ABSOLUTE_LONG},
+	// 0x50
+	{"BVC", RELATIVE}, {"EOR", DIRECT_INDIRECT_Y}, {"EOR", DIRECT_INDIRECT}, {"EOR", STACK_RELATIVE_INDIRECT_Y},
+	{"MVN", BLOCK_MOVE}, {"EOR", DIRECT_X}, {"LSR", DIRECT_X}, {"EOR", DIRECT_INDIRECT_LONG_Y},
+	{"CLI", IMPLIED}, {"EOR", ABSOLUTE_Y}, {"PHY", IMPLIED}, {"TCD", IMPLIED},
+	{"JML", ABSOLUTE_LONG}, {"EOR", ABSOLUTE_X}, {"LSR", ABSOLUTE_X}, {"EOR", ABSOLUTE_LONG_X},
+	// 0x60
+	{"RTS", IMPLIED}, {"ADC", DIRECT_INDIRECT_X}, {"PER", RELATIVE_LONG}, {"ADC", STACK_RELATIVE},
+	{"STZ", DIRECT}, {"ADC", DIRECT}, {"ROR", DIRECT}, {"ADC", DIRECT_INDIRECT_LONG},
+	{"PLA", IMPLIED}, {"ADC", IMMEDIATE_M}, {"ROR", ACCUMULATOR}, {"RTL", IMPLIED},
+	{"JMP", ABSOLUTE_INDIRECT}, {"ADC", ABSOLUTE}, {"ROR", ABSOLUTE}, {"ADC", ABSOLUTE_LONG},
+	// 0x70
+	{"BVS", RELATIVE}, {"ADC", DIRECT_INDIRECT_Y}, {"ADC", DIRECT_INDIRECT}, {"ADC", STACK_RELATIVE_INDIRECT_Y},
+	{"STZ", DIRECT_X}, {"ADC", DIRECT_X}, {"ROR", DIRECT_X}, {"ADC", DIRECT_INDIRECT_LONG_Y},
+	{"SEI", IMPLIED}, {"ADC", ABSOLUTE_Y}, {"PLY", IMPLIED}, {"TDC", IMPLIED},
+	{"JMP", ABSOLUTE_INDIRECT_X}, {"ADC", ABSOLUTE_X}, {"ROR", ABSOLUTE_X}, {"ADC", ABSOLUTE_LONG_X},
+	// 0x80
+	{"BRA", RELATIVE}, {"STA", DIRECT_INDIRECT_X}, {"BRL", RELATIVE_LONG}, {"STA", STACK_RELATIVE},
+	{"STY", DIRECT}, {"STA", DIRECT}, {"STX", DIRECT}, {"STA", DIRECT_INDIRECT_LONG},
+	{"DEY", IMPLIED}, {"BIT", IMMEDIATE_M}, {"TXA", IMPLIED}, {"PHB", IMPLIED},
+	{"STY", ABSOLUTE}, {"STA", ABSOLUTE}, {"STX", ABSOLUTE}, {"STA", ABSOLUTE_LONG},
+	// 0x90
+	{"BCC", RELATIVE}, {"STA", DIRECT_INDIRECT_Y}, {"STA", DIRECT_INDIRECT}, {"STA", STACK_RELATIVE_INDIRECT_Y},
+	{"STY", DIRECT_X}, {"STA", DIRECT_X}, {"STX", DIRECT_Y}, {"STA", DIRECT_INDIRECT_LONG_Y},
+	{"TYA", IMPLIED}, {"STA", ABSOLUTE_Y}, {"TXS", IMPLIED}, {"TXY", IMPLIED},
+	{"STZ", ABSOLUTE}, {"STA", ABSOLUTE_X}, {"STZ", ABSOLUTE_X}, {"STA", ABSOLUTE_LONG_X},
+	// 0xA0
+	{"LDY", IMMEDIATE_X}, {"LDA", DIRECT_INDIRECT_X}, {"LDX", IMMEDIATE_X}, {"LDA", STACK_RELATIVE},
+	{"LDY", DIRECT}, {"LDA", DIRECT}, {"LDX", DIRECT}, {"LDA", DIRECT_INDIRECT_LONG},
+	{"TAY", IMPLIED}, {"LDA", IMMEDIATE_M}, {"TAX", IMPLIED}, {"PLB", IMPLIED},
+	{"LDY", ABSOLUTE}, {"LDA", ABSOLUTE}, {"LDX", ABSOLUTE}, {"LDA", ABSOLUTE_LONG},
+	// 0xB0
+	{"BCS", RELATIVE}, {"LDA", DIRECT_INDIRECT_Y}, {"LDA", DIRECT_INDIRECT}, {"LDA", STACK_RELATIVE_INDIRECT_Y},
+	{"LDY", DIRECT_X}, {"LDA", DIRECT_X}, {"LDX", DIRECT_Y}, {"LDA", DIRECT_INDIRECT_LONG_Y},
+	{"CLV", IMPLIED}, {"LDA", ABSOLUTE_Y}, {"TSX", IMPLIED}, {"TYX", IMPLIED},
+	{"LDY", ABSOLUTE_X}, {"LDA", ABSOLUTE_X}, {"LDX", ABSOLUTE_Y}, {"LDA", ABSOLUTE_LONG_X},
+	// 0xC0
+	{"CPY", IMMEDIATE_X}, {"CMP", DIRECT_INDIRECT_X}, {"REP", IMMEDIATE8}, {"CMP", STACK_RELATIVE},
+	{"CPY", DIRECT}, {"CMP", DIRECT}, {"DEC", DIRECT}, {"CMP", DIRECT_INDIRECT_LONG},
+	{"INY", IMPLIED}, {"CMP", IMMEDIATE_M}, {"DEX", IMPLIED}, {"WAI", IMPLIED},
+	{"CPY", ABSOLUTE}, {"CMP", ABSOLUTE}, {"DEC", ABSOLUTE}, {"CMP", ABSOLUTE_LONG},
+	// 0xD0
+	{"BNE", RELATIVE}, {"CMP", DIRECT_INDIRECT_Y}, {"CMP", DIRECT_INDIRECT}, {"CMP", STACK_RELATIVE_INDIRECT_Y},
+	{"PEI", DIRECT_INDIRECT}, {"CMP", DIRECT_X}, {"DEC", DIRECT_X}, {"CMP", DIRECT_INDIRECT_LONG_Y},
+	{"CLD", IMPLIED}, {"CMP", ABSOLUTE_Y}, {"PHX", IMPLIED}, {"STP", IMPLIED},
+	{"JML", ABSOLUTE_INDIRECT_LONG}, {"CMP", ABSOLUTE_X}, {"DEC", ABSOLUTE_X}, {"CMP", ABSOLUTE_LONG_X},
+	// 0xE0
+	{"CPX", IMMEDIATE_X}, {"SBC", DIRECT_INDIRECT_X}, {"SEP", IMMEDIATE8}, {"SBC", STACK_RELATIVE},
+	{"CPX", DIRECT}, {"SBC", DIRECT}, {"INC", DIRECT}, {"SBC", DIRECT_INDIRECT_LONG},
+	{"INX", IMPLIED}, {"SBC", IMMEDIATE_M}, {"NOP", IMPLIED}, {"XBA", IMPLIED},
+	{"CPX", ABSOLUTE}, {"SBC", ABSOLUTE}, {"INC", ABSOLUTE}, {"SBC", ABSOLUTE_LONG},
+	// 0xF0
+	{"BEQ", RELATIVE}, {"SBC", DIRECT_INDIRECT_Y}, {"SBC", DIRECT_INDIRECT}, {"SBC", STACK_RELATIVE_INDIRECT_Y},
+	{"PEA", ABSOLUTE}, {"SBC", DIRECT_X}, {"INC", DIRECT_X}, {"SBC", DIRECT_INDIRECT_LONG_Y},
+	{"SED", IMPLIED}, {"SBC", ABSOLUTE_Y}, {"PLX", IMPLIED}, {"XCE", IMPLIED},
+	{"JSR", ABSOLUTE_INDIRECT_X}, {"SBC", ABSOLUTE_X}, {"INC", ABSOLUTE_X}, {"SBC", ABSOLUTE_LONG_X},
+}
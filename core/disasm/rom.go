@@ -0,0 +1,54 @@
+package disasm
+
+import (
+	"errors"
+
+	cart "github.com/pokemium/gsnes/core/cartridge"
+)
+
+// ROM maps a raw ROM image into SNES address space (LoROM or HiROM is detected from its header)
+//
+// Unmapped addresses (e.g. WRAM, I/O) read 0.
+func ROM(romData []uint8) (Memory, error) {
+	t := cart.DetectROMType(romData)
+	if len(romData)&0x3FF == 0x200 {
+		romData = romData[0x200:] // copier header
+	}
+	if len(romData) == 0 {
+		return nil, errors.New("ROM is empty")
+	}
+
+	switch t {
+	case cart.LoROM:
+		return func(addr uint32) uint8 {
+			bank, ofs := addr>>16, addr&0xFFFF
+			if isWRAM(bank) || (ofs < 0x8000 && bank&0x7F < 0x40) {
+				return 0
+			}
+			return romData[((bank&0x7F)*0x8000+(ofs&0x7FFF))%uint32(len(romData))]
+		}, nil
+
+	case cart.HiROM, cart.ExHiROM:
+		return func(addr uint32) uint8 {
+			bank, ofs := addr>>16, addr&0xFFFF
+			if isWRAM(bank) || (ofs < 0x8000 && bank&0x7F < 0x40) {
+				return 0
+			}
+			idx := (bank&0x3F)<<16 | ofs
+			if t == cart.ExHiROM && bank < 0x80 {
+				idx += 0x40_0000 // banks 40-7D are upper 4MB
+			}
+			return romData[idx%uint32(len(romData))]
+		}, nil
+	}
+	return nil, errors.New("invalid ROM header")
+}
+
+func isWRAM(bank uint32) bool {
+	return bank == 0x7E || bank == 0x7F
+}
+
+// Vector reads a 16bit interrupt vector in bank 0 (e.g. 0xFFFC: RESET in emulation mode)
+func Vector(mem Memory, addr uint16) uint32 {
+	return uint32(mem(uint32(addr))) | uint32(mem(uint32(addr+1)))<<8
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/pokemium/iro"
+)
+
+// Pixel formats for ReadFrame (byte order in memory)
+const (
+	FORMAT_RGBA8888 = "RGBA8888"
+	FORMAT_BGRA8888 = "BGRA8888"
+	FORMAT_RGB565   = "RGB565" // little endian
+)
+
+// PixelSize returns bytes per pixel of the format (0 if invalid)
+func PixelSize(format string) int {
+	switch strings.ToUpper(format) {
+	case FORMAT_RGBA8888, FORMAT_BGRA8888:
+		return 4
+	case FORMAT_RGB565:
+		return 2
+	}
+	return 0
+}
+
+// RGB555 -> output format (precomputed)
+var (
+	rgba8888Table [0x8000]uint32
+	bgra8888Table [0x8000]uint32
+	rgb565Table   [0x8000]uint16
+)
+
+func init() {
+	for i := range rgba8888Table {
+		r5, g5, b5 := uint32(i&0x1F), uint32((i>>5)&0x1F), uint32((i>>10)&0x1F)
+		r, g, b := r5<<3|r5>>2, g5<<3|g5>>2, b5<<3|b5>>2 // 5bit -> 8bit
+		rgba8888Table[i] = r | g<<8 | b<<16 | 0xFF<<24
+		bgra8888Table[i] = b | g<<8 | r<<16 | 0xFF<<24
+		rgb565Table[i] = uint16(r5<<11 | (g5<<1|g5>>4)<<5 | b5)
+	}
+}
+
+// ReadFrame converts the current frame into buf in one pass (a copy, not zero-copy), which must have w*h*PixelSize(format) bytes at least (w, h: Resolution())
+func (s *sfc) ReadFrame(buf []uint8, format string) error {
+	r := s.ppu.r
+	frame := r.frameBuffer()
+	size := PixelSize(format)
+	if size == 0 {
+		return fmt.Errorf("invalid pixel format: %s", format)
+	}
+	if len(buf) < len(frame)*size {
+		return fmt.Errorf("buffer is too small: %d < %d", len(buf), len(frame)*size)
+	}
+
+	switch strings.ToUpper(format) {
+	case FORMAT_RGBA8888:
+		writePixels32(buf, frame, &rgba8888Table)
+	case FORMAT_BGRA8888:
+		writePixels32(buf, frame, &bgra8888Table)
+	case FORMAT_RGB565:
+		buf = buf[:len(frame)*2]
+		for i, c := range frame {
+			binary.LittleEndian.PutUint16(buf[i*2:], rgb565Table[c&0x7FFF])
+		}
+	}
+	return nil
+}
+
+func writePixels32(buf []uint8, frame []iro.RGB555, table *[0x8000]uint32) {
+	buf = buf[:len(frame)*4]
+	for i, c := range frame {
+		binary.LittleEndian.PutUint32(buf[i*4:], table[c&0x7FFF])
+	}
+}
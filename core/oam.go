@@ -21,6 +21,7 @@ type oam struct {
 	reload uint16 // Reload value (OAMADD.0-8)
 	addr   uint16 // unit is byte
 	rotate bool   // OAMADD.15
+	first  uint8  // OBJ which has the highest priority (OBJ #0 unless OAMADD.15 is set)
 
 	objs         [128]obj
 	buf          [544]uint8 // for read
@@ -45,6 +46,18 @@ func (o *oam) reset() {
 	// TODO
 	o.size = objSizes[0]
 	o.reload = 0x0
+	o.resetAddr()
+}
+
+// Reload OAM address on OAMADD write and at the start of vblank
+//
+// Priority rotation uses the reloaded address, so writing OAMDATA doesn't change the first OBJ.
+func (o *oam) resetAddr() {
+	o.addr = (o.reload << 1) & 0x3FF
+	o.first = 0
+	if o.rotate {
+		o.first = uint8(o.addr>>2) & 0x7F
+	}
 }
 
 func (o *oam) write(val uint8) {
@@ -7,6 +7,7 @@ type palette struct {
 	idx         uint8
 	is2ndAccess bool
 	lastWritten uint8
+	writes      uint64 // write counter for debug viewers
 }
 
 func newPalette() *palette {
@@ -46,6 +47,7 @@ func (p *palette) write(val uint8) {
 		// odd(2nd)
 		val &= 0x7f
 		p.buf[p.idx] = iro.RGB555(uint16(val)<<8 | uint16(memo))
+		p.writes++
 		p.idx++
 		p.is2ndAccess = false
 		return
@@ -7,8 +7,6 @@ import (
 	"github.com/pokemium/iro"
 )
 
-var fblankScreen = [HORIZONTAL * VERTICAL]iro.RGB555{}
-
 type ppu struct {
 	c              *sfc
 	vram           vram
@@ -25,18 +23,17 @@ type ppu struct {
 
 	inFBlank bool // INIDISP.7
 	r        *renderer
+	dot      bool // use dot renderer (scanline renderer by default)
+	isPAL    bool // PAL region (STAT78.4)
+	dotLine  bool // current line is drawn by dot renderer
 
 	openbus [2]uint8 // 0: PPU1, 1: PPU2
 
-	mpy struct {
-		a      uint16
-		second bool
-		b      uint8
-		result uint24
-	}
+	mpy uint24 // MPYL, MPYM, MPYH (M7A * M7B)
+
 	stat78 struct {
 		latch     bool // bit6
-		interlace bool // 2nd frame on interlace
+		interlace bool // bit7, field (2nd frame on interlace)
 	}
 	// 0: OPHCT, 1: OPVCT
 	opct [2]struct {
@@ -68,6 +65,7 @@ func (p *ppu) reset() {
 	for i := range p.pal.buf {
 		p.pal.buf[i] = iro.RGB555(0x0000)
 	}
+	p.pal.writes++
 
 	p.hcount, p.vcount = INIT_CYCLE/4+1, 0
 	p.inHBlank, p.inVBlank = false, false
@@ -80,19 +78,38 @@ func (p *ppu) reset() {
 func (p *ppu) newline(cyclesLate int64) {
 	p.vcount++
 
+	p.dotLine = p.dot && p.vcount < p.vblankLine()
+	if p.dotLine {
+		p.r.mosaic.scanline(p.vcount)
+		p.r.startLine(p.vcount, p.inFBlank)
+	}
+
 	switch p.vcount {
-	case VERTICAL + 1:
+	case p.vblankLine():
 		// start VBlank
 		p.setVBlank(true, cyclesLate)
 		p.c.earlyExit = true
 		p.c.frame++
 
-	case TOTAL_SCANLINE:
+	case p.totalLines():
 		p.vcount = 0
 		p.setVBlank(false, cyclesLate) // End of VBlank
 	}
 }
 
+// 262 lines in NTSC, 312 lines in PAL
+func (p *ppu) totalLines() uint16 {
+	if p.isPAL {
+		return TOTAL_SCANLINE_PAL
+	}
+	return TOTAL_SCANLINE
+}
+
+// VBlank starts at line 225 (240 in overscan)
+func (p *ppu) vblankLine() uint16 {
+	return uint16(p.r.lines) + 1
+}
+
 func (p *ppu) setNMI(cyclesLate int64) {
 	w := p.c.w
 	old := bit(w.nmitimen, 7) && bit(w.rdnmi, 7)
@@ -108,16 +125,74 @@ func (p *ppu) setNMI(cyclesLate int64) {
 // (x, y) = (274, any)
 func (p *ppu) startHBlank() {
 	p.inHBlank = true
-	if p.vcount > 0 && p.vcount < VERTICAL && !p.inFBlank {
-		p.r.drawScanline(p.vcount)
+	if p.dotLine {
+		p.drawDots(HORIZONTAL)
+		p.r.endLine(p.vcount)
+		return
+	}
+
+	if p.vcount > 0 && p.vcount < p.vblankLine() {
+		p.r.mosaic.scanline(p.vcount)
+		if p.inFBlank {
+			p.r.blankLine(p.vcount)
+		} else {
+			p.r.drawScanline(p.vcount)
+		}
 	}
 }
 
+// Dot renderer draws the current line up to current dot before PPU registers are written
+//
+// Most registers (CGRAM, color math, windows, Mode 7) take effect from the written dot.
+// BG scroll, tilemap and tile data addresses are used when each 8px tile is fetched, see latchBg.
+func (p *ppu) catchUp() {
+	if !p.dotLine {
+		return
+	}
+	p.drawDots(p.dotX())
+}
+
+// Current dot in the visible line (CPU may run ahead of hcount in the middle of an instruction)
+func (p *ppu) dotX() int {
+	x := int(p.hcount) + int(p.c.s.RelativeCycles/4) - FIRST_DOT
+	if x > HORIZONTAL {
+		x = HORIZONTAL
+	}
+	return x
+}
+
+// The tile shown at a dot is fetched in the 8 dots before it,
+// so BG fetch registers written in the middle of the line apply from the next tile of the BG.
+// Until then, the BG is drawn with the registers before the write.
+func (p *ppu) latchBg(b *bg) {
+	if !p.dotLine || p.r.mode == 7 {
+		return
+	}
+	x := p.dotX()
+	if x <= 0 || x >= HORIZONTAL || b.until > x {
+		return // nothing fetched yet, or the latched tile isn't shown yet
+	}
+	b.old = *b._bg
+	b.until = x + 8 - int((uint16(x)+b.sc[0].val)&0b111)
+}
+
+func (p *ppu) drawDots(end int) {
+	start := p.r.drawn
+	if start >= end {
+		return
+	}
+	if p.inFBlank {
+		p.r.blankDots(start, end)
+		return
+	}
+	p.r.drawDots(p.vcount, start, end)
+}
+
 func (p *ppu) setVBlank(b bool, cyclesLate int64) {
 	if b {
-		// (H, V) = (0, 225)
+		// (H, V) = (0, 225 or 240)
 		p.inVBlank = true
-		p.c.s.Schedule(&p.nmiEvent, 2-cyclesLate) // (H, V) = (0.5, 225)
+		p.c.s.Schedule(&p.nmiEvent, 2-cyclesLate) // (H, V) = (0.5, 225 or 240)
 		return
 	}
 
@@ -137,6 +212,10 @@ func (p *ppu) incrementHCount(cyclesLate int64) {
 		if p.vcount == 0 {
 			// toggle interlace frame
 			p.stat78.interlace = !p.stat78.interlace
+			p.r.newFrame(p.stat78.interlace)
+			if !p.inFBlank {
+				p.r.obj.rangeOver, p.r.obj.timeOver = false, false // STAT77
+			}
 		}
 
 	case 6:
@@ -148,14 +227,14 @@ func (p *ppu) incrementHCount(cyclesLate int64) {
 		}
 
 	case 10:
-		if p.vcount == 225 {
+		if p.vcount == p.vblankLine() {
 			if !p.inFBlank {
-				p.oam.addr = (p.oam.reload << 1) & 0x3FF
+				p.oam.resetAddr()
 			}
 		}
 
 	case 32:
-		if p.vcount == 225 {
+		if p.vcount == p.vblankLine() {
 			if autoJoypadRead := bit(w.nmitimen, 0); autoJoypadRead {
 				w.ajr = true
 				for i := range w.joypads {
@@ -168,7 +247,7 @@ func (p *ppu) incrementHCount(cyclesLate int64) {
 		}
 
 	case 92:
-		if p.vcount == 225 {
+		if p.vcount == p.vblankLine() {
 			w.ajr = false
 		}
 
@@ -180,8 +259,8 @@ func (p *ppu) incrementHCount(cyclesLate int64) {
 		p.startHBlank()
 
 	case 278:
-		if p.vcount < 225 {
-			// (H, V) = (278, 0..224)
+		if p.vcount < p.vblankLine() {
+			// (H, V) = (278, 0..224 or 0..239)
 			// perform HDMA transfers
 			hdmaen := dma.hdmaen
 
@@ -6,7 +6,7 @@ func (p *ppu) readIO(addr uint, defaultVal uint8) uint8 {
 		return p.openbus[0]
 
 	case 0x34, 0x35, 0x36: // MPY
-		p.openbus[0] = uint8(p.mpy.result.u32() >> (8 * (addr - 0x34)))
+		p.openbus[0] = uint8(p.mpy.u32() >> (8 * (addr - 0x34)))
 		return p.openbus[0]
 
 	case 0x37: // SLHV
@@ -43,13 +43,16 @@ func (p *ppu) readIO(addr uint, defaultVal uint8) uint8 {
 		return p.openbus[1]
 
 	case 0x3E: // STAT77
-		val := uint8(0x01)
+		val := uint8(0x01) | (p.openbus[0] & 0x10)
+		val = setBit(val, 6, p.r.obj.rangeOver)
+		val = setBit(val, 7, p.r.obj.timeOver)
 		p.openbus[0] = val
 		return val
 
 	case 0x3F: // STAT78
 		r := &p.stat78
 		val := uint8(0x03)
+		val = setBit(val, 4, p.isPAL)
 		val = setBit(val, 6, r.latch)
 		r.latch = false
 		p.opct[0].second, p.opct[1].second = false, false
@@ -62,12 +65,14 @@ func (p *ppu) readIO(addr uint, defaultVal uint8) uint8 {
 }
 
 func (p *ppu) writeIO(addr uint, val uint8) {
+	p.catchUp()
 	v := &p.vram
-	bg1, bg2, bg3, bg4, objs := p.r.bg1, p.r.bg2, p.r.bg3, p.r.bg4, p.r.objs[:]
+	bg1, bg2, bg3, bg4, obj := p.r.bg1, p.r.bg2, p.r.bg3, p.r.bg4, p.r.obj
 
 	switch addr {
 	case 0x00: // INIDISP
 		p.inFBlank = bit(val, 7)
+		p.r.brightness = val & 0b1111
 
 	case 0x01: // OBSEL
 		o := &p.oam
@@ -85,6 +90,7 @@ func (p *ppu) writeIO(addr uint, val uint8) {
 			o.rotate = bit(val, 7)
 		}
 		o.reload &= 0x01FF
+		o.resetAddr()
 
 	case 0x04: // OAMDATA
 		p.oam.write(val)
@@ -101,10 +107,15 @@ func (p *ppu) writeIO(addr uint, val uint8) {
 		bg4.tilesize = tilesizes[(val>>7)&0b1]
 
 	case 0x06: // MOSAIC
-		// TODO
+		p.r.mosaic.size = uint16(val>>4) + 1
+		bg1.mosaic = bit(val, 0)
+		bg2.mosaic = bit(val, 1)
+		bg3.mosaic = bit(val, 2)
+		bg4.mosaic = bit(val, 3)
 
 	case 0x07, 0x08, 0x09, 0x0A: // BGNSC
 		bg := [4]*bg{p.r.bg1, p.r.bg2, p.r.bg3, p.r.bg4}[addr-0x07]
+		p.latchBg(bg)
 		bg.size = [2]uint16{32, 32}
 		if bit(val, 0) {
 			bg.size[0] = 64
@@ -115,27 +126,39 @@ func (p *ppu) writeIO(addr uint, val uint8) {
 		bg.tilemapAddr = (uint(val>>2) * (2 * KB)) & 0xFFFF
 
 	case 0x0B: // BGNBA12
+		p.latchBg(bg1)
+		p.latchBg(bg2)
 		p.r.bg1.tileDataAddr = uint(val&0b1111) * (8 * KB)
 		p.r.bg2.tileDataAddr = uint((val>>4)&0b1111) * (8 * KB)
 	case 0x0C: // BGNBA34
+		p.latchBg(bg3)
+		p.latchBg(bg4)
 		p.r.bg3.tileDataAddr = uint(val&0b1111) * (8 * KB)
 		p.r.bg4.tileDataAddr = uint((val>>4)&0b1111) * (8 * KB)
 
 	case 0x0D, 0x0F, 0x11, 0x13: // BGnHOFS
 		n := (addr - 0x0D) / 2
 		bg := [4]*bg{bg1, bg2, bg3, bg4}[n]
+		p.latchBg(bg)
 		prev := uint16(bg.sc[0].prev)
 		bg.sc[0].prev = val
 		bg.sc[0].reg = (uint16(val) << 8) | (prev & 0xFFF8) | ((bg.sc[0].reg >> 8) & 7)
 		bg.sc[0].val = bg.sc[0].reg & 0x3FF
+		if n == 0 {
+			p.r.m7.hofs = sext13(p.r.m7.write(val)) // M7HOFS
+		}
 
 	case 0x0E, 0x10, 0x12, 0x14: // BGnVOFS
 		n := (addr - 0x0E) / 2
 		bg := [4]*bg{bg1, bg2, bg3, bg4}[n]
+		p.latchBg(bg)
 		prev := uint16(bg.sc[1].prev)
 		bg.sc[1].prev = val
 		bg.sc[1].reg = (uint16(val)<<8 | prev)
 		bg.sc[1].val = bg.sc[1].reg & 0x3FF
+		if n == 0 {
+			p.r.m7.vofs = sext13(p.r.m7.write(val)) // M7VOFS
+		}
 
 	case 0x15: // VMAIN
 		v.incType = increment(val >> 7)
@@ -158,29 +181,28 @@ func (p *ppu) writeIO(addr uint, val uint8) {
 	case 0x18, 0x19: // VMDATA
 		p.vram.write(addr == 0x19, val)
 
-	case 0x1B: // M7A
-		mul := func(a int) {
-			b := int(int8(p.mpy.b))
-			ab := uint32(a * b)
-			p.mpy.result = toU24(ab)
+	case 0x1A: // M7SEL
+		m := &p.r.m7
+		m.over = val >> 6
+		if m.over == 1 {
+			m.over = M7_WRAP
 		}
+		m.vflip, m.hflip = bit(val, 1), bit(val, 0)
 
-		if p.mpy.second {
-			p.mpy.second = false
-			p.mpy.a = uint16(val)<<8 | (p.mpy.a & 0x00FF)
-			mul(int(int16(p.mpy.a)))
-			return
-		}
-		p.mpy.second = true
-		p.mpy.a = (p.mpy.a & 0xFF00) | uint16(val)
-		mul(int(int16(p.mpy.a)))
-
+	case 0x1B: // M7A
+		p.r.m7.a = int16(p.r.m7.write(val))
+		p.mpy = p.r.m7.mul()
 	case 0x1C: // M7B
-		p.mpy.b = val
-		a := int(int16(p.mpy.a))
-		b := int(int8(p.mpy.b))
-		ab := uint32(a * b)
-		p.mpy.result = toU24(ab)
+		p.r.m7.b = int16(p.r.m7.write(val))
+		p.mpy = p.r.m7.mul()
+	case 0x1D: // M7C
+		p.r.m7.c = int16(p.r.m7.write(val))
+	case 0x1E: // M7D
+		p.r.m7.d = int16(p.r.m7.write(val))
+	case 0x1F: // M7X
+		p.r.m7.x = sext13(p.r.m7.write(val))
+	case 0x20: // M7Y
+		p.r.m7.y = sext13(p.r.m7.write(val))
 
 	case 0x21: // CGADD
 		p.pal.setAddr(val)
@@ -193,43 +215,79 @@ func (p *ppu) writeIO(addr uint, val uint8) {
 		p.r.w.win2.mask[i+0] = windowMask(val >> 2)
 		p.r.w.win1.mask[i+1] = windowMask(val >> 4)
 		p.r.w.win2.mask[i+1] = windowMask(val >> 6)
+		p.r.w.dirty = true
 
 	case 0x26: // WH0
 		p.r.w.win1.left = val
+		p.r.w.dirty = true
 	case 0x27: // WH1
 		p.r.w.win1.right = val
+		p.r.w.dirty = true
 	case 0x28: // WH2
 		p.r.w.win2.left = val
+		p.r.w.dirty = true
 	case 0x29: // WH3
 		p.r.w.win2.right = val
+		p.r.w.dirty = true
 
 	case 0x2A: // WBGLOG
 		p.r.w.logic[0] = maskLogic((val >> 0) & 0b11)
 		p.r.w.logic[1] = maskLogic((val >> 2) & 0b11)
 		p.r.w.logic[2] = maskLogic((val >> 4) & 0b11)
 		p.r.w.logic[3] = maskLogic((val >> 6) & 0b11)
+		p.r.w.dirty = true
 
 	case 0x2B: // WOBJLOG
 		p.r.w.logic[4] = maskLogic((val >> 0) & 0b11)
 		p.r.w.logic[5] = maskLogic((val >> 2) & 0b11)
+		p.r.w.dirty = true
 
 	case 0x2C: // TM
 		bg1.mainsc = bit(val, 0)
 		bg2.mainsc = bit(val, 1)
 		bg3.mainsc = bit(val, 2)
 		bg4.mainsc = bit(val, 3)
-		mainsc := bit(val, 4)
-		for i := range objs {
-			objs[i].mainsc = mainsc
-		}
+		obj.mainsc = bit(val, 4)
 	case 0x2D: // TS
 		bg1.subsc = bit(val, 0)
 		bg2.subsc = bit(val, 1)
 		bg3.subsc = bit(val, 2)
 		bg4.subsc = bit(val, 3)
-		subsc := bit(val, 4)
-		for i := range objs {
-			objs[i].subsc = subsc
+		obj.subsc = bit(val, 4)
+
+	case 0x2E: // TMW
+		for i := range p.r.w.tmw {
+			p.r.w.tmw[i] = bit(val, i)
+		}
+	case 0x2F: // TSW
+		for i := range p.r.w.tsw {
+			p.r.w.tsw[i] = bit(val, i)
+		}
+
+	case 0x30: // CGWSEL
+		m := &p.r.math
+		m.clip = mathRegion(val >> 6)
+		m.prevent = mathRegion((val >> 4) & 0b11)
+		m.addSub = bit(val, 1)
+		m.direct = bit(val, 0)
+
+	case 0x31: // CGADSUB
+		m := &p.r.math
+		m.subtract = bit(val, 7)
+		m.half = bit(val, 6)
+		for i := range m.enable {
+			m.enable[i] = bit(val, i)
 		}
+
+	case 0x32: // COLDATA
+		p.r.math.setFixedColor(val)
+
+	case 0x33: // SETINI
+		p.r.extbg = bit(val, 6)
+		p.r.pseudoHires = bit(val, 3)
+		p.r.overscan = bit(val, 2)
+		p.r.objInterlace = bit(val, 1)
+		p.r.interlace = bit(val, 0)
+		p.r.setBgMode(p.mode)
 	}
 }
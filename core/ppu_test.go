@@ -0,0 +1,107 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/pokemium/iro"
+)
+
+// Mode 0 BG1 with 8px columns, tiles are from the tilemap row
+func dotScene(t *testing.T, row ...uint16) *ppu {
+	t.Helper()
+	s := New().(*sfc)
+	p := s.ppu
+	p.reset()
+	s.DotRenderer(true)
+	p.writeIO(0x00, 0x0F) // INIDISP: full brightness
+	p.writeIO(0x05, 0x00) // BGMODE: Mode 0
+	p.writeIO(0x07, 0x04) // BG1SC: tilemap at 0x0400 (word)
+	p.writeIO(0x0B, 0x01) // BG12NBA: tiles at 0x1000 (word)
+	p.writeIO(0x2C, 0x01) // TM: BG1
+
+	for i := 0; i < 8; i++ {
+		p.vram.buf[0x1000+8*1+i] = 0x00FF // tile 1: color 1
+		p.vram.buf[0x1000+8*2+i] = 0xFF00 // tile 2: color 2
+		p.vram.buf[0x1000+8*3+i] = 0x6DB6 // tile 3: color 1, 2, 3, 1, 2, 3, 1, 2
+	}
+	for i := 0; i < 32; i++ {
+		p.vram.buf[0x0400+i] = row[i%len(row)]
+	}
+	p.pal.buf[0], p.pal.buf[1], p.pal.buf[2], p.pal.buf[3] = 0x0000, 0x001F, 0x03E0, 0x7C00
+
+	p.vcount = 0
+	p.newline(0) // line 1
+	return p
+}
+
+// Write PPU register at dot x of the line
+func writeAt(p *ppu, x int, addr uint, vals ...uint8) {
+	p.c.s.RelativeCycles = 0
+	p.hcount = uint16(FIRST_DOT + x)
+	for _, val := range vals {
+		p.writeIO(addr, val)
+	}
+}
+
+// Finish line 1 and return it
+func endLine(p *ppu) []iro.RGB555 {
+	p.hcount = 274
+	p.startHBlank()
+	return p.r.frame[:HORIZONTAL]
+}
+
+func TestDotRendererCGRAM(t *testing.T) {
+	p := dotScene(t, 1)
+	writeAt(p, 100, 0x21, 1)          // CGADD
+	writeAt(p, 100, 0x22, 0x00, 0x7C) // CGDATA: color 1 = blue
+	line := endLine(p)
+
+	for _, tt := range []struct {
+		x    int
+		want iro.RGB555
+	}{{0, 0x001F}, {99, 0x001F}, {100, 0x7C00}, {255, 0x7C00}} {
+		if line[tt.x] != tt.want {
+			t.Errorf("x=%d: got %04X, want %04X", tt.x, line[tt.x], tt.want)
+		}
+	}
+}
+
+// BG1HOFS written in the middle of a tile applies from the next tile
+func TestDotRendererHOFS(t *testing.T) {
+	p := dotScene(t, 1, 2)      // even columns are red, odd columns are green
+	writeAt(p, 100, 0x0D, 4, 0) // BG1HOFS = 4
+	line := endLine(p)
+
+	for _, tt := range []struct {
+		x    int
+		want iro.RGB555
+	}{
+		{96, 0x001F}, {99, 0x001F}, // column 12 (HOFS=0)
+		{100, 0x001F}, {103, 0x001F}, // column 12 is already fetched
+		{104, 0x03E0}, {107, 0x03E0}, // column 13 from 108 (HOFS=4)
+		{108, 0x001F}, {111, 0x001F}, // column 14 from 112
+		{112, 0x001F}, {115, 0x001F},
+		{116, 0x03E0},
+	} {
+		if line[tt.x] != tt.want {
+			t.Errorf("x=%d: got %04X, want %04X", tt.x, line[tt.x], tt.want)
+		}
+	}
+}
+
+// Segment which starts in a mosaic block takes the left pixel of the block
+func TestDotRendererMosaic(t *testing.T) {
+	p := dotScene(t, 3)
+	p.writeIO(0x06, 0x31) // MOSAIC: 4x4, BG1
+	p.r.mosaic.scanline(p.vcount)
+	writeAt(p, 102, 0x21, 0x80) // CGADD: split the line at 102
+	line := endLine(p)
+
+	for x := 96; x < 112; x++ {
+		// color of the left pixel of the block is 1, 2, 3, 1, ... (8px tile)
+		want := p.pal.buf[1+(x-x%4)%8%3]
+		if line[x] != want {
+			t.Errorf("x=%d: got %04X, want %04X", x, line[x], want)
+		}
+	}
+}
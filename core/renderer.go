@@ -8,16 +8,39 @@ type renderer struct {
 	vram    *vram
 	pal     *palette
 	oam     *oam
-	screens [2][HORIZONTAL * VERTICAL]iro.RGB555 // 0: main, 1: sub
+	screens [2][HORIZONTAL * OVERSCAN]iro.RGB555      // 0: main, 1: sub
+	srcs    [2][HORIZONTAL]layerID                    // layer of each pixel in current line, 0: main, 1: sub
+	out     [2][HORIZONTAL]iro.RGB555                 // blended line, 0: main(odd pixels in hires), 1: sub(even pixels in hires)
+	frame   [HORIZONTAL * 2 * OVERSCAN * 2]iro.RGB555 // main and sub are blended by color math
+	width   int                                       // frame width (256 or 512)
+	lines   int                                       // visible lines of current frame (224 or 239)
+	height  int                                       // frame height (lines, x2 in interlace)
+	field   bool                                      // current field (STAT78.7)
 
-	mode                   uint8   // BG Mode(0..7)
-	layers                 []layer // idx 0 is backdrop
-	bg1, bg2, bg3, bg4     *bg
-	bg1h, bg2h, bg3h, bg4h *bg
-	objs                   [4]*objl
-	backdrop               layer
-	bg3a                   bool // BGMODE.3
-	w                      windowSystem
+	isPAL      bool  // PAL region outputs 239 lines regardless of overscan
+	brightness uint8 // INIDISP.0-3 (0: black, 15: full)
+	drawn      int   // pixels of current line already drawn (dot renderer draws a line in segments)
+	lineHires  bool  // current line has 512px segments
+
+	cgram [OVERSCAN][256]iro.RGB555 // CGRAM at the end of each line (for debugger)
+
+	mode               uint8         // BG Mode(0..7)
+	layers             [5]layer      // BG1..BG4, OBJ (idx is layerID)
+	lbufs              [5]lineBuffer // line of each layer before compositing
+	prio               priorityTable
+	bg1, bg2, bg3, bg4 *bg
+	obj                *objl
+	bg3a               bool // BGMODE.3
+	extbg              bool // SETINI.6
+	pseudoHires        bool // SETINI.3
+	interlace          bool // SETINI.0
+	objInterlace       bool // SETINI.1
+	overscan           bool // SETINI.2
+	m7                 mode7
+	mosaic             mosaic
+	tmp                lineBuffer // for horizontal mosaic
+	w                  windowSystem
+	math               colorMath
 }
 
 func newRenderer(vram *vram, pal *palette, oam *oam) *renderer {
@@ -27,11 +50,8 @@ func newRenderer(vram *vram, pal *palette, oam *oam) *renderer {
 		oam:  oam,
 	}
 	r.bg1, r.bg2, r.bg3, r.bg4 = newBg(r, 1), newBg(r, 2), newBg(r, 3), newBg(r, 4)
-	r.bg1h, r.bg2h, r.bg3h, r.bg4h = newBgH(r.bg1), newBgH(r.bg2), newBgH(r.bg3), newBgH(r.bg4)
-	r.backdrop = newBackdrop(&r.pal.buf[0])
-
-	obj0, obj1, obj2, obj3 := newObjLayer(r, 0), newObjLayer(r, 1), newObjLayer(r, 2), newObjLayer(r, 3)
-	r.objs = [4]*objl{obj0, obj1, obj2, obj3}
+	r.obj = newObjLayer(r)
+	r.layers = [5]layer{r.bg1, r.bg2, r.bg3, r.bg4, r.obj}
 	return r
 }
 
@@ -42,25 +62,289 @@ func (r *renderer) reset() {
 		r.screens[0][i] = iro.RGB555(0x0000)
 		r.screens[1][i] = iro.RGB555(0x0000)
 	}
+	for i := range r.frame {
+		r.frame[i] = iro.RGB555(0x0000)
+	}
+	r.interlace, r.objInterlace, r.overscan = false, false, false
+	r.width = HORIZONTAL
+	r.brightness = 0
+	r.newFrame(false)
+	r.math = colorMath{}
+	r.mosaic = mosaic{size: 1}
 }
 
+// Scanline renderer draws a whole line at once with the registers at the start of hblank
 func (r *renderer) drawScanline(y uint16) {
-	for _, l := range r.layers {
-		if l.enable() {
-			if l.isMain() {
-				l.drawScanline(r.screens[0][HORIZONTAL*(y-1):], y, 0, 256)
+	r.startLine(y, false)
+	r.drawDots(y, 0, HORIZONTAL)
+	r.endLine(y)
+}
+
+// Forced blank line is black
+func (r *renderer) blankLine(y uint16) {
+	r.startLine(y, true)
+	r.blankDots(0, HORIZONTAL)
+	r.endLine(y)
+}
+
+// Dot renderer draws a line in segments between register writes, so mid-line changes take effect at the written dot (BG scroll and addresses at the next tile)
+//
+//	startLine -> drawDots(0, x1) -> (register write) -> drawDots(x1, x2) -> ... -> endLine
+//
+// Sprites aren't evaluated in forced blank.
+func (r *renderer) startLine(y uint16, fblank bool) {
+	if fblank {
+		r.obj.tiles = r.obj.tiles[:0]
+		r.obj.record(y)
+	} else {
+		r.obj.evaluate(y)
+	}
+	r.drawn, r.lineHires = 0, false
+	r.bg1.until, r.bg2.until, r.bg3.until, r.bg4.until = 0, 0, 0, 0
+}
+
+// Draw pixels [start, end) of the line
+func (r *renderer) drawDots(y uint16, start, end int) {
+	if start >= end {
+		return
+	}
+
+	ofs := HORIZONTAL * int(y-1)
+	main := scanline{buf: r.screens[0][ofs : ofs+HORIZONTAL], src: r.srcs[0][:]}
+	sub := scanline{buf: r.screens[1][ofs : ofs+HORIZONTAL], src: r.srcs[1][:], sub: true}
+
+	r.w.update()
+	hires := r.hires() || r.pseudoHires
+	r.lineHires = r.lineHires || hires
+
+	// sub screen backdrop is fixed color (CGRAM[0] in hires)
+	backdrop := r.math.fixed
+	if hires {
+		backdrop = r.pal.buf[0]
+	}
+	r.compose(&main, y, r.pal.buf[0], start, end)
+	r.compose(&sub, y, backdrop, start, end)
+
+	out0, out1 := r.out[0][start:end], r.out[1][start:end]
+	window := r.w.masks[WINDOW_COLOR][start:end]
+	r.math.blend(out0, main.buf[start:end], sub.buf[start:end], main.src[start:end], sub.src[start:end], window)
+	r.light(out0)
+	if hires {
+		// sub screen pixels are also blended with main screen
+		r.math.blend(out1, sub.buf[start:end], main.buf[start:end], sub.src[start:end], main.src[start:end], window)
+		r.light(out1)
+	} else {
+		copy(out1, out0) // 256px segment in 512px line is doubled
+	}
+	r.drawn = end
+}
+
+func (r *renderer) blankDots(start, end int) {
+	for x := start; x < end; x++ {
+		r.out[0][x], r.out[1][x] = iro.RGB555(0x0000), iro.RGB555(0x0000)
+	}
+	r.drawn = end
+}
+
+func (r *renderer) endLine(y uint16) {
+	r.drawn = HORIZONTAL
+	r.writeLine(y, r.lineHires)
+	copy(r.cgram[y-1][:], r.pal.buf)
+}
+
+// 16-step master brightness (INIDISP.0-3), scaled by (b+1)/16 as hardware does and 0 is black
+var lightTable = func() (t [16][32]uint16) {
+	for b := 1; b < len(t); b++ {
+		for c := range t[b] {
+			t[b][c] = uint16(c * (b + 1) / 16)
+		}
+	}
+	return t
+}()
+
+func (r *renderer) light(line []iro.RGB555) {
+	if r.brightness == 15 {
+		return
+	}
+	t := &lightTable[r.brightness]
+	for x, c := range line {
+		line[x] = iro.RGB555(t[c&0x1F] | t[(c>>5)&0x1F]<<5 | t[(c>>10)&0x1F]<<10)
+	}
+}
+
+// Draw each layer into its own line, then resolve the priority of each pixel
+//
+// Layer which won each pixel is recorded in l.src for color math.
+func (r *renderer) compose(l *scanline, y uint16, backdrop iro.RGB555, start, end int) {
+	var lines [5]*scanline
+	for i, layer := range r.layers {
+		if !layer.enable() || !((l.sub && layer.isSub()) || (!l.sub && layer.isMain())) {
+			continue
+		}
+		line := r.lbufs[i].scanline(HORIZONTAL)
+		line.mask, line.sub = r.w.mask(layer.id(), l.sub), l.sub
+		layer.drawScanline(line, y, start, end)
+		lines[i] = line
+	}
+
+	for x := start; x < end; x++ {
+		c, src, best := backdrop, LAYER_BACKDROP, uint8(0)
+		for i, line := range lines {
+			if line == nil || line.src[x] == LAYER_BACKDROP {
+				continue
 			}
-			if l.isSub() {
-				l.drawScanline(r.screens[1][HORIZONTAL*(y-1):], y, 0, 256)
+			if p := r.prio[i][line.prio[x]]; p > best {
+				c, src, best = line.buf[x], line.src[x], p
 			}
 		}
+		l.buf[x], l.src[x] = c, src
+	}
+}
+
+// Mode5,6 render BGs in 512px
+func (r *renderer) hires() bool {
+	return r.mode == 5 || r.mode == 6
+}
+
+// Latch frame size at the start of each frame
+//
+// In interlace, frame width isn't reset because the lines of the previous field are displayed with current field.
+func (r *renderer) newFrame(field bool) {
+	r.field = field
+	if r.vram.mapped {
+		r.vram.cache.flush() // VRAM may be edited from outside
+	}
+	r.lines = VERTICAL
+	if r.overscan {
+		r.lines = OVERSCAN
+	}
+
+	// PAL outputs 239 lines, and lines below 224 are black without overscan
+	rows := r.lines
+	if r.isPAL {
+		rows = OVERSCAN
+	}
+
+	r.height = rows
+	if r.interlace {
+		r.height *= 2
+	} else {
+		r.width = HORIZONTAL
+	}
+	if rows > r.lines {
+		r.clearRows(r.lines*r.height/rows, r.height)
+	}
+}
+
+// Write blended line into frame buffer
+//
+// Once a hires line is drawn, the frame becomes 512px wide and 256px lines are doubled horizontally.
+func (r *renderer) writeLine(y uint16, hires bool) {
+	if hires && r.width == HORIZONTAL {
+		r.widen()
+	}
+
+	line := int(y - 1)
+	if r.height > OVERSCAN {
+		line = 2*line + btoi(r.field)
+	}
+	row := r.frame[r.width*line : r.width*(line+1)]
+	switch {
+	case r.width == HORIZONTAL:
+		copy(row, r.out[0][:])
+	case hires:
+		for x := 0; x < HORIZONTAL; x++ {
+			row[2*x], row[2*x+1] = r.out[1][x], r.out[0][x]
+		}
+	default:
+		for x := 0; x < HORIZONTAL; x++ {
+			row[2*x], row[2*x+1] = r.out[0][x], r.out[0][x]
+		}
+	}
+}
+
+// Fill rows [from, to) of frame buffer with black
+func (r *renderer) clearRows(from, to int) {
+	rows := r.frame[r.width*from : r.width*to]
+	for i := range rows {
+		rows[i] = iro.RGB555(0x0000)
+	}
+}
+
+// Widen lines already drawn into 512px
+func (r *renderer) widen() {
+	// backward so as not to overwrite pixels that aren't copied yet
+	for line := r.height - 1; line >= 0; line-- {
+		for x := HORIZONTAL - 1; x >= 0; x-- {
+			c := r.frame[HORIZONTAL*line+x]
+			r.frame[2*HORIZONTAL*line+2*x], r.frame[2*HORIZONTAL*line+2*x+1] = c, c
+		}
+	}
+	r.width = HORIZONTAL * 2
+}
+
+// MOSAIC
+type mosaic struct {
+	size    uint16 // MOSAIC.4-7 (1..16)
+	counter uint16 // Vertical mosaic counter
+	y       uint16 // First line of current mosaic block
+}
+
+// Update vertical mosaic counter at the start of each line
+func (m *mosaic) scanline(y uint16) {
+	if y == 1 {
+		m.counter, m.y = m.size, 1
+		return
+	}
+
+	m.counter--
+	if m.counter == 0 {
+		// new size is used from the next block when MOSAIC is written mid-frame
+		m.counter, m.y = m.size, y
 	}
 }
 
 func (r *renderer) frameBuffer() []iro.RGB555 {
-	return r.screens[0][:]
+	return r.frame[:r.width*r.height]
 }
 
+// Priority of each layer's pixel in current BG mode (the higher wins, 0 isn't displayed)
+//
+// idx: [LAYER_BG1..LAYER_OBJ][priority of the pixel]
+type priorityTable [5][4]uint8
+
+type layerPriority struct {
+	id   layerID
+	prio uint8
+}
+
+// Build the priority table from the order (back to front)
+func newPriorityTable(order ...layerPriority) priorityTable {
+	t := priorityTable{}
+	for i, p := range order {
+		t[p.id][p.prio] = uint8(i + 1)
+	}
+	return t
+}
+
+var (
+	BG1_0, BG1_1 = layerPriority{LAYER_BG1, 0}, layerPriority{LAYER_BG1, 1}
+	BG2_0, BG2_1 = layerPriority{LAYER_BG2, 0}, layerPriority{LAYER_BG2, 1}
+	BG3_0, BG3_1 = layerPriority{LAYER_BG3, 0}, layerPriority{LAYER_BG3, 1}
+	BG4_0, BG4_1 = layerPriority{LAYER_BG4, 0}, layerPriority{LAYER_BG4, 1}
+	OBJ_0, OBJ_1 = layerPriority{LAYER_OBJ, 0}, layerPriority{LAYER_OBJ, 1}
+	OBJ_2, OBJ_3 = layerPriority{LAYER_OBJ, 2}, layerPriority{LAYER_OBJ, 3}
+)
+
+var (
+	MODE0_PRIORITY     = newPriorityTable(BG4_0, BG3_0, OBJ_0, BG4_1, BG3_1, OBJ_1, BG2_0, BG1_0, OBJ_2, BG2_1, BG1_1, OBJ_3)
+	MODE1_PRIORITY     = newPriorityTable(BG3_0, OBJ_0, BG3_1, OBJ_1, BG2_0, BG1_0, OBJ_2, BG2_1, BG1_1, OBJ_3)
+	MODE1_BG3_PRIORITY = newPriorityTable(BG3_0, OBJ_0, OBJ_1, BG2_0, BG1_0, OBJ_2, BG2_1, BG1_1, OBJ_3, BG3_1) // BGMODE.3
+	MODE2_PRIORITY     = newPriorityTable(BG2_0, OBJ_0, BG1_0, OBJ_1, BG2_1, OBJ_2, BG1_1, OBJ_3)               // Mode2..5
+	MODE6_PRIORITY     = newPriorityTable(OBJ_0, BG1_0, OBJ_1, OBJ_2, BG1_1, OBJ_3)
+	MODE7_PRIORITY     = newPriorityTable(BG2_0, OBJ_0, BG1_0, OBJ_1, BG2_1, OBJ_2, OBJ_3) // BG2 is EXTBG
+)
+
 func (r *renderer) setBgMode(m uint8) {
 	r.mode = m
 	bg1, bg2, bg3, bg4 := r.bg1, r.bg2, r.bg3, r.bg4
@@ -75,15 +359,15 @@ func (r *renderer) setBgMode(m uint8) {
 		bg2.color, bg2.palOfs = COLOR_2BPP, 0x20
 		bg3.color, bg3.palOfs = COLOR_2BPP, 0x40
 		bg4.color, bg4.palOfs = COLOR_2BPP, 0x60
-		r.layers = []layer{r.backdrop, r.bg4, r.bg3, r.objs[0], r.bg4h, r.bg3h, r.objs[1], r.bg2, r.bg1, r.objs[2], r.bg2h, r.bg1h, r.objs[3]}
+		r.prio = MODE0_PRIORITY
 
 	case 1:
 		bg1.color, bg1.palOfs = COLOR_4BPP, 0x00
 		bg2.color, bg2.palOfs = COLOR_4BPP, 0x00
 		bg3.color, bg3.palOfs = COLOR_2BPP, 0x00
-		r.layers = []layer{r.backdrop, r.bg3, r.objs[0], r.bg3h, r.objs[1], r.bg2, r.bg1, r.objs[2], r.bg2h, r.bg1h, r.objs[3]}
+		r.prio = MODE1_PRIORITY
 		if r.bg3a {
-			r.layers = []layer{r.backdrop, r.bg3, r.objs[0], r.objs[1], r.bg2, r.bg1, r.objs[2], r.bg2h, r.bg1h, r.objs[3], r.bg3h}
+			r.prio = MODE1_BG3_PRIORITY
 		}
 
 	case 2, 3, 4, 5:
@@ -101,15 +385,17 @@ func (r *renderer) setBgMode(m uint8) {
 			bg1.color, bg1.palOfs = COLOR_4BPP, 0x00
 			bg2.color, bg2.palOfs = COLOR_2BPP, 0x00
 		}
-		r.layers = []layer{r.backdrop, r.bg2, r.objs[0], r.bg1, r.objs[1], r.bg2h, r.objs[2], r.bg1h, r.objs[3]}
+		r.prio = MODE2_PRIORITY
 
 	case 6:
 		bg1.color = COLOR_4BPP
-		r.layers = []layer{r.backdrop, r.objs[0], r.bg1, r.objs[1], r.objs[2], r.bg1h, r.objs[3]}
+		r.prio = MODE6_PRIORITY
 
 	case 7:
 		bg1.color = COLOR_8BPP
-		bg2.color = COLOR_7BPP
-		r.layers = []layer{r.backdrop, r.bg2, r.objs[0], r.bg1, r.objs[1], r.bg2h, r.objs[2], r.objs[3]}
+		if r.extbg {
+			bg2.color = COLOR_7BPP
+		}
+		r.prio = MODE7_PRIORITY
 	}
 }
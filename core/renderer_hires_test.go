@@ -0,0 +1,70 @@
+package core
+
+import "testing"
+
+// BG1 of mode 5 (4bpp, 16px wide tiles in 512px), only the leftmost pixel of each tile is color 1
+func newHiresTest() *sfc {
+	s := New().(*sfc)
+	p := s.ppu
+	p.reset()
+	p.writeIO(0x00, 0x0F) // INIDISP
+	p.writeIO(0x07, 0x00) // BG1SC: tilemap at 0
+	p.writeIO(0x0B, 0x01) // BG12NBA: BG1 tiles at word 0x1000
+	for i := 0; i < 8; i++ {
+		p.vram.buf[0x1000+i] = 0x0080 // tile 0
+	}
+	p.pal.buf[0], p.pal.buf[1] = 0x0001, 0x1234
+	return s
+}
+
+func TestHires(t *testing.T) {
+	s := newHiresTest()
+	p := s.ppu
+	p.r.drawScanline(1)
+	if w, _ := s.Resolution(); w != HORIZONTAL {
+		t.Fatalf("width: %d", w)
+	}
+
+	p.writeIO(0x05, 0x05) // BGMODE
+	p.writeIO(0x2C, 0x01) // TM: BG1
+	p.writeIO(0x2D, 0x01) // TS: BG1
+	p.r.drawScanline(2)
+	if w, _ := s.Resolution(); w != 2*HORIZONTAL {
+		t.Fatalf("width: %d", w)
+	}
+
+	// line 1 is doubled horizontally
+	line1, line2 := p.r.frame[:512], p.r.frame[512:1024]
+	if line1[0] != 0x0001 || line1[1] != 0x0001 {
+		t.Errorf("line 1: %v %v", line1[0], line1[1])
+	}
+
+	// even pixels are from sub screen, odd pixels are from main screen
+	for x, want := range []uint16{0x1234, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0x1234, 1} {
+		if got := uint16(line2[x]); got != want {
+			t.Errorf("line 2: x=%d %04X, want %04X", x, got, want)
+		}
+	}
+	if len(s.FrameBuffer()) != 2*HORIZONTAL*VERTICAL {
+		t.Errorf("frame buffer: %d", len(s.FrameBuffer()))
+	}
+}
+
+func TestPseudoHires(t *testing.T) {
+	s := newHiresTest()
+	p := s.ppu
+	p.writeIO(0x05, 0x01) // BGMODE: 8px wide 4bpp tiles
+	p.writeIO(0x2C, 0x01) // TM: BG1
+	p.writeIO(0x33, 0x08) // SETINI: pseudo hires
+	p.r.drawScanline(1)
+	if w, _ := s.Resolution(); w != 2*HORIZONTAL {
+		t.Fatalf("width: %d", w)
+	}
+
+	// sub screen is backdrop (CGRAM[0])
+	for x, want := range []uint16{1, 0x1234, 1, 1} {
+		if got := uint16(p.r.frame[x]); got != want {
+			t.Errorf("x=%d %04X, want %04X", x, got, want)
+		}
+	}
+}
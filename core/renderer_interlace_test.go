@@ -0,0 +1,42 @@
+package core
+
+import "testing"
+
+func TestInterlace(t *testing.T) {
+	s := New().(*sfc)
+	p := s.ppu
+	p.reset()
+	p.writeIO(0x00, 0x0F) // INIDISP
+	p.writeIO(0x33, 0x05) // SETINI: interlace, overscan
+
+	p.r.newFrame(false)
+	if w, h := s.Resolution(); w != HORIZONTAL || h != 2*OVERSCAN {
+		t.Fatalf("resolution: %dx%d", w, h)
+	}
+	if p.vblankLine() != OVERSCAN+1 {
+		t.Fatalf("VBlank starts at line %d", p.vblankLine())
+	}
+
+	// lines of even field go to even rows, and odd field to odd rows
+	p.pal.buf[0] = 0x001F
+	p.r.drawScanline(1)
+	p.r.drawScanline(OVERSCAN)
+	p.r.newFrame(true)
+	p.pal.buf[0] = 0x03E0
+	p.r.drawScanline(1)
+	for _, row := range []int{0, 2*OVERSCAN - 2} {
+		if c := p.r.frame[row*HORIZONTAL]; c != 0x001F {
+			t.Errorf("row %d: %v", row, c)
+		}
+	}
+	if c := p.r.frame[HORIZONTAL]; c != 0x03E0 {
+		t.Errorf("row 1: %v", c)
+	}
+
+	// progressive, 224 lines
+	p.writeIO(0x33, 0x00)
+	p.r.newFrame(false)
+	if w, h := s.Resolution(); w != HORIZONTAL || h != VERTICAL || p.vblankLine() != VERTICAL+1 {
+		t.Fatalf("resolution: %dx%d", w, h)
+	}
+}
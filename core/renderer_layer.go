@@ -14,40 +14,60 @@ import (
 */
 var ofs16 = [4]uint16{0, 1, 16, 17}
 
-type layer interface {
-	drawScanline(buf []iro.RGB555, y uint16, start, end int)
-	enable() bool
-	isMain() bool
-	isSub() bool
-}
+// layerID represents which layer drew the pixel.
+//
+// LAYER_BG1..LAYER_BACKDROP is the same order as CGADSUB.0-5
+type layerID uint8
+
+const (
+	LAYER_BG1 layerID = iota
+	LAYER_BG2
+	LAYER_BG3
+	LAYER_BG4
+	LAYER_OBJ // OBJ Palette 4..7
+	LAYER_BACKDROP
+	LAYER_OBJ_NOMATH // OBJ Palette 0..3 (never affected by color math)
+)
 
-type backdrop struct {
-	color *iro.RGB555
+// Line of a layer, or main screen or sub screen of the current line
+type scanline struct {
+	buf  []iro.RGB555
+	src  []layerID
+	prio []uint8 // Priority of each pixel (BG: 0..1, OBJ: 0..3)
+	mask []bool  // Pixels masked by window (nil if the window is disabled for the layer)
+	sub  bool    // Sub screen takes even pixels of 512px line in Mode5,6
 }
 
-func newBackdrop(color *iro.RGB555) *backdrop {
-	return &backdrop{
-		color: color,
+func (l *scanline) put(x int, c iro.RGB555, id layerID, prio uint8) {
+	if l.mask != nil && l.mask[x] {
+		return
 	}
+	l.buf[x], l.src[x], l.prio[x] = c, id, prio
 }
 
-func (b *backdrop) enable() bool {
-	return true
+// Pixels which aren't drawn are LAYER_BACKDROP
+type lineBuffer struct {
+	buf  [HORIZONTAL * 2]iro.RGB555
+	src  [HORIZONTAL * 2]layerID
+	prio [HORIZONTAL * 2]uint8
+	line scanline
 }
 
-func (b *backdrop) isMain() bool {
-	return true
-}
-
-func (b *backdrop) isSub() bool {
-	return true
+// Clear the buffer and return the first `width` pixels as a scanline
+func (b *lineBuffer) scanline(width int) *scanline {
+	for x := 0; x < width; x++ {
+		b.src[x] = LAYER_BACKDROP
+	}
+	b.line = scanline{buf: b.buf[:width], src: b.src[:width], prio: b.prio[:width]}
+	return &b.line
 }
 
-func (b *backdrop) drawScanline(buf []iro.RGB555, y uint16, start, end int) {
-	c := *b.color
-	for i := start; i < end; i++ {
-		buf[i] = c
-	}
+type layer interface {
+	drawScanline(l *scanline, y uint16, start, end int)
+	id() layerID
+	enable() bool
+	isMain() bool
+	isSub() bool
 }
 
 type _bg struct {
@@ -61,6 +81,7 @@ type _bg struct {
 	tilesize      uint16    // BGMODE.4-7, 8(8x8) or 16(16x16)
 	palOfs        int
 	sc            [2]scroll // 0: X, 1: Y
+	mosaic        bool      // MOSAIC.0-3
 }
 
 type scroll struct {
@@ -71,7 +92,10 @@ type scroll struct {
 
 type bg struct {
 	*_bg
-	prio bool // BGMap.13
+
+	// Dot renderer: registers before a mid-line write, used for pixels before until (see ppu.latchBg)
+	old   _bg
+	until int
 }
 
 func newBg(r *renderer, index int) *bg {
@@ -84,6 +108,10 @@ func newBg(r *renderer, index int) *bg {
 	}
 }
 
+func (b *bg) id() layerID {
+	return LAYER_BG1 + layerID(b.index-1)
+}
+
 func (b *bg) enable() bool {
 	return b.color != DISABLED
 }
@@ -96,135 +124,214 @@ func (b *bg) isSub() bool {
 	return b.subsc
 }
 
-func (b *bg) drawScanline(buf []iro.RGB555, y uint16, start, end int) {
-	vram := b.r.vram.buf
-	bgmap := vram[(b.tilemapAddr/2)&0x7FFF:]
+func (b *bg) drawScanline(l *scanline, y uint16, start, end int) {
+	m := &b.r.mosaic
 
-	scx, scy := b.sc[0].val, b.sc[1].val
+	// In mode7, vertical mosaic of BG2(EXTBG) is controlled by BG1
+	vertical := b.mosaic
+	if b.r.mode == 7 {
+		vertical = b.r.bg1.mosaic
+	}
+	if vertical {
+		y = m.y
+	}
 
-	y = (y + scy) % (b.size[1] * 8)
-	row := y / b.tilesize // 上から何タイル目？
+	hires := b.r.hires()
+	if hires && b.r.interlace {
+		// Mode5,6: BGs are rendered in 448(478) lines
+		y = y<<1 | btou16(b.r.field && !vertical)
+	}
+	if !hires && (!b.mosaic || m.size == 1) {
+		b.render(l, y, start, end)
+		return
+	}
 
-	for x := start; x < end; x++ {
-		xx := (uint16(x) + scx) % (b.size[0] * 8)
+	// draw into temporary line, then pick pixels for the screen
+	//   Mode5,6: 512px line, sub screen takes even pixels and main screen takes odd pixels
+	//   Mosaic: stretch the left pixel of each block
+	width, size := HORIZONTAL, int(m.size)
+	if hires {
+		width, size = HORIZONTAL*2, size*2
+	}
+	if !b.mosaic {
+		size = 1
+	}
 
-		/*
-			16x16のときに対象がどの象限にいるか
-			[0 1]
-			[2 3]
-		*/
-		quadrant := 0
-		if x%int(b.tilesize) >= 8 {
-			quadrant += 1
+	// a segment of dot renderer may start in a mosaic block, so draw from the left pixel of the block
+	tmp := &b.r.tmp
+	from := start * width / HORIZONTAL
+	b.render(tmp.scanline(width), y, from-from%size, end*width/HORIZONTAL)
+
+	for x := start; x < end; x++ {
+		sx := x
+		if hires {
+			sx = 2*x + btoi(!l.sub)
 		}
-		if y%b.tilesize >= 8 {
-			quadrant += 2
+		sx -= sx % size
+		if tmp.src[sx] != LAYER_BACKDROP {
+			l.put(x, tmp.buf[sx], tmp.src[sx], tmp.prio[sx])
 		}
+	}
+}
 
-		// (タイルサイズ関係なく)8pxずつ描画
-		if xx&0b111 == 0 {
-			col := uint16(xx / b.tilesize) // 左から何タイル目？
+func (b *bg) render(l *scanline, y uint16, start, end int) {
+	if b.r.mode == 7 {
+		b.drawMode7Scanline(l, y, start, end)
+		return
+	}
 
-			idx := (row&0x1F)*32 + (col & 0x1F)
-			if col >= 32 {
-				idx += 0x800 / 2
-			}
-			if row >= 32 {
-				idx += 0x800 * (b.size[0] / 32) / 2
-			}
-			entry := bgmap[idx]
+	until := b.until
+	if b.r.hires() {
+		until *= 2
+	}
+	if start < until {
+		old := &bg{_bg: &b.old}
+		if until >= end {
+			old.renderTiles(l, y, start, end)
+			return
+		}
+		old.renderTiles(l, y, start, until)
+		start = until
+	}
+	b.renderTiles(l, y, start, end)
+}
 
-			tileID := (entry & 1023) + ofs16[quadrant]
+// Draw pixels [start, end) of BG Mode0-6 line
+func (b *bg) renderTiles(l *scanline, y uint16, start, end int) {
+	id := b.id()
 
-			palID := int((entry >> 10) & 0b111)
-			hflip, vflip := bit(entry, 14), bit(entry, 15)
+	// tile width, tile height
+	tw, th := b.tilesize, b.tilesize
 
-			if b.prio != bit(entry, 13) {
-				continue
-			}
+	scx, scy := b.sc[0].val, b.sc[1].val
+	if b.r.hires() {
+		// Mode5,6: 512px line, tile width is always 16px
+		tw, scx = 16, scx<<1
+	}
 
-			switch b.color {
-			case COLOR_2BPP:
-				ofs := b.palOfs + (4 * palID)
-				pal := b.r.pal.buf[ofs : ofs+4]
-
-				tileStart := ((b.tileDataAddr / 2) + 8*uint(tileID)) & 0x7FFF
-				tile := vram[tileStart : tileStart+8] // 2bpp: 1タイル = 16バイト
-
-				flipedY := flip(8, vflip, int(y&0b111))
-				rowdata := tile[flipedY]
-				planes := [2]uint8{uint8(rowdata), uint8(rowdata >> 8)}
-
-				// 1pxずつ描画
-				for i := 0; i < 8; i++ {
-					colorID := uint8(0)
-					for j := 0; j < 2; j++ {
-						colorID += ((planes[j] >> (7 - i)) & 0b1) << j
-					}
-					if colorID != 0 {
-						buf[x+flip(8, hflip, i)] = pal[colorID]
-					}
-				}
+	opt := b.index <= 2 && (b.r.mode == 2 || b.r.mode == 4 || b.r.mode == 6)
 
-			case COLOR_4BPP:
-				ofs := b.palOfs + 16*palID
-				pal := b.r.pal.buf[ofs : ofs+16]
+	// (タイルサイズ関係なく)8pxずつ描画
+	for x := start - int((uint16(start)+scx)&0b111); x < end; x += 8 {
+		hofs, vofs := scx, scy
+		if opt {
+			hofs, vofs = b.offsetPerTile(x, scx, scy)
+		}
 
-				tileStart := ((b.tileDataAddr / 2) + 16*uint(tileID)) & 0x7FFF
-				tile := vram[tileStart : tileStart+16] // 4bpp: 1タイル = 32バイト
+		xx := (uint16(x) + hofs) % (b.size[0] * tw)
+		yy := (y + vofs) % (b.size[1] * th)
+		col := xx / tw // 左から何タイル目？
+		row := yy / th // 上から何タイル目？
+		entry := b.entry(col, row)
 
-				flipedY := flip(8, vflip, int(y&0b111))
-				rowdata := [2]uint16{tile[flipedY], tile[flipedY+8]}
-				planes := [4]uint8{
-					uint8(rowdata[0]), uint8(rowdata[0] >> 8),
-					uint8(rowdata[1]), uint8(rowdata[1] >> 8),
-				}
+		palID := int((entry >> 10) & 0b111)
+		prio := btou8(bit(entry, 13))
+		hflip, vflip := bit(entry, 14), bit(entry, 15)
 
-				// 1pxずつ描画
-				for i := 0; i < 8; i++ {
-					colorID := uint8(0)
-					for j := 0; j < 4; j++ {
-						colorID += ((planes[j] >> (7 - i)) & 0b1) << j
-					}
-					if colorID != 0 {
-						buf[x+flip(8, hflip, i)] = pal[colorID]
-					}
+		/*
+			16x16のときに対象がどの象限にいるか
+			[0 1]
+			[2 3]
+		*/
+		quadrant := 0
+		if tw == 16 && (xx%tw >= 8) != hflip {
+			quadrant += 1
+		}
+		if th == 16 && (yy%th >= 8) != vflip {
+			quadrant += 2
+		}
+		tileID := (entry & 1023) + ofs16[quadrant]
+
+		var pal []iro.RGB555
+		switch b.color {
+		case COLOR_2BPP:
+			ofs := b.palOfs + (4 * palID)
+			pal = b.r.pal.buf[ofs : ofs+4]
+		case COLOR_4BPP:
+			ofs := b.palOfs + 16*palID
+			pal = b.r.pal.buf[ofs : ofs+16]
+		case COLOR_8BPP:
+			pal = b.r.pal.buf[0:256]
+		default:
+			crash("Invalid color format: %d", b.color)
+		}
+		direct := b.color == COLOR_8BPP && b.r.math.direct
+
+		// 2bpp: 1タイル = 16バイト, 4bpp: 32バイト, 8bpp: 64バイト
+		tileStart := ((b.tileDataAddr / 2) + tileWords[b.color]*uint(tileID)) & 0x7FFF
+		pixels := b.r.vram.tileRow(b.color, tileStart, flip(8, vflip, int(yy&0b111)))
+
+		// 1pxずつ描画
+		for i := 0; i < 8; i++ {
+			colorID := pixels[i]
+			if px := x + flip(8, hflip, i); colorID != 0 && px >= start && px < end {
+				c := pal[colorID]
+				if direct {
+					c = directColor(uint8(palID), colorID)
 				}
+				l.put(px, c, id, prio)
+			}
+		}
+	}
+}
 
-			case COLOR_7BPP:
-				// crash("7bpp in Mode%d", b.r.mode)
-
-			case COLOR_8BPP:
-				pal := b.r.pal.buf[0:256]
-
-				tileStart := ((b.tileDataAddr / 2) + 32*uint(tileID)) & 0x7FFF
-				tile := vram[tileStart : tileStart+32] // 8bpp: 1タイル = 64バイト
-
-				flipedY := flip(8, vflip, int(y&0b111))
-				rowdata := [4]uint16{tile[flipedY], tile[flipedY+8], tile[flipedY+16], tile[flipedY+24]}
-				planes := [8]uint8{
-					uint8(rowdata[0]), uint8(rowdata[0] >> 8),
-					uint8(rowdata[1]), uint8(rowdata[1] >> 8),
-					uint8(rowdata[2]), uint8(rowdata[2] >> 8),
-					uint8(rowdata[3]), uint8(rowdata[3] >> 8),
-				}
+// Tilemap entry of the tile at (col, row)
+func (b *bg) entry(col, row uint16) uint16 {
+	idx := (row&0x1F)*32 + (col & 0x1F)
+	if col >= 32 {
+		idx += 0x800 / 2
+	}
+	if row >= 32 {
+		idx += 0x800 * (b.size[0] / 32) / 2
+	}
+	return b.r.vram.buf[(uint(b.tilemapAddr/2)+uint(idx))&0x7FFF]
+}
 
-				// 1pxずつ描画
-				for i := 0; i < 8; i++ {
-					colorID := uint8(0)
-					for j := 0; j < 8; j++ {
-						colorID += ((planes[j] >> (7 - i)) & 0b1) << j
-					}
-					if colorID != 0 {
-						buf[x+flip(8, hflip, i)] = pal[colorID]
-					}
-				}
+// Tilemap entry of the tile at (x, y) in pixels (BG3 for offset-per-tile)
+func (b *bg) entryAt(x, y uint16) uint16 {
+	x, y = x%(b.size[0]*b.tilesize), y%(b.size[1]*b.tilesize)
+	return b.entry(x/b.tilesize, y/b.tilesize)
+}
 
-			default:
-				crash("Invalid color format: %d", b.color)
+// Offset-per-tile(Mode2,4,6): BG3 tilemap gives the scroll of each column for BG1 and BG2
+//
+// The row at BG3VOFS has horizontal offsets and the next row has vertical offsets.
+// In Mode4, there is only one row and bit15 selects horizontal or vertical.
+// Columns are 8px(16px in Mode6) and the leftmost column isn't affected.
+func (b *bg) offsetPerTile(x int, scx, scy uint16) (uint16, uint16) {
+	width := uint16(8)
+	if b.r.hires() {
+		width = 16 // scx is doubled too
+	}
+	fine := scx & (width - 1)
+	if x+int(fine) < int(width) {
+		return scx, scy
+	}
+	col := uint16(x+int(fine)) / width
+
+	bg3 := b.r.bg3
+	valid := 12 + b.index // BG1: bit13, BG2: bit14
+	lx := 8*(col-1) + (bg3.sc[0].val &^ 7)
+	hofs := bg3.entryAt(lx, bg3.sc[1].val)
+	if b.r.mode == 4 {
+		if bit(hofs, valid) {
+			if bit(hofs, 15) {
+				scy = hofs & 0x3FF
+			} else {
+				scx = fine | (hofs&0x3F8)*(width/8)
 			}
 		}
+		return scx, scy
 	}
+
+	vofs := bg3.entryAt(lx, bg3.sc[1].val+8)
+	if bit(hofs, valid) {
+		scx = fine | (hofs&0x3F8)*(width/8)
+	}
+	if bit(vofs, valid) {
+		scy = vofs & 0x3FF
+	}
+	return scx, scy
 }
 
 func (b *bg) String() string {
@@ -235,26 +342,42 @@ func (b *bg) String() string {
 	return line1 + "\n" + line2
 }
 
-func newBgH(b *bg) *bg {
-	return &bg{
-		_bg:  b._bg,
-		prio: true,
-	}
-}
-
+// OBJ layer draws all sprites, the priority(OBJ0..3) of each pixel is resolved by compositor
 type objl struct {
 	r             *renderer
-	index         uint8 // 0,1,2,3: OBJ0,OBJ1,OBJ2,OBJ3
 	mainsc, subsc bool
+	limit         bool              // 32 sprites and 34 tiles per line (disabling it removes flicker)
+	items         [128]int          // OBJs on current line (from the highest priority one)
+	tiles         []objTile         // tiles fetched on current line (from the lowest priority one)
+	rangeOver     bool              // STAT77.6
+	timeOver      bool              // STAT77.7
+	shown         [OVERSCAN]objList // OBJs shown on each line (for debugger)
+}
+
+type objList struct {
+	n    int
+	objs [128]uint8
 }
 
-func newObjLayer(r *renderer, index uint8) *objl {
+// 8x1 sliver of an OBJ fetched on current line
+type objTile struct {
+	idx int // OBJ #N
+	x   int // X position on the screen (-7..255)
+	col int // 左から何タイル目か
+}
+
+func newObjLayer(r *renderer) *objl {
 	return &objl{
 		r:     r,
-		index: index,
+		limit: true,
+		tiles: make([]objTile, 0, 128*8),
 	}
 }
 
+func (o *objl) id() layerID {
+	return LAYER_OBJ
+}
+
 func (o *objl) enable() bool {
 	return true
 }
@@ -267,51 +390,108 @@ func (o *objl) isSub() bool {
 	return o.subsc
 }
 
-func (o *objl) drawScanline(buf []iro.RGB555, y uint16, start, end int) {
+// Sprite evaluation at the start of each line
+//
+// Range: up to 32 OBJs on the line are taken from the first OBJ in OAM order.
+// Time: up to 34 tiles of them are fetched from the last OBJ, so the tiles of higher priority OBJs are dropped.
+func (o *objl) evaluate(y uint16) {
 	oam := o.r.oam
 
-	highest := 0
-
-	if oam.rotate {
-		highest = int((oam.addr >> 2) & 0x7F) // OBJ #N
+	n := 0
+	for i := 0; i < 128; i++ {
+		idx := (int(oam.first) + i) & 0x7F
+		if !o.inRange(idx, y) {
+			continue
+		}
+		if n == 32 {
+			o.rangeOver = true
+			if o.limit {
+				break
+			}
+		}
+		o.items[n] = idx
+		n++
 	}
 
-	lowest := highest - 1
-	if lowest == -1 {
-		lowest = 127
-	}
-	i := lowest
-	for {
-		obj := &oam.objs[i]
-		if obj.prio == o.index {
-			top := int(obj.y)
-			height := oam.size[0]
-			if obj.large {
-				height = oam.size[1]
-			}
+	o.tiles = o.tiles[:0]
+fetch:
+	for i := n - 1; i >= 0; i-- {
+		idx := o.items[i]
+		obj := &oam.objs[idx]
+		width := oam.size[btoi(obj.large)]
 
-			if top <= int(y) && int(y) < top+height {
-				o.drawObjScanline(i, buf, y, start, end)
+		x := int(obj.x)
+		if x >= 256 {
+			x -= 512 // negative X
+		}
+		for col := 0; col < width/8; col++ {
+			tx := x + 8*col
+			// NOTE: OBJs at X=256 fetch all tiles
+			if obj.x != 256 && (tx <= -8 || tx >= 256) {
+				continue
 			}
+			if len(o.tiles) == 34 {
+				o.timeOver = true
+				if o.limit {
+					break fetch
+				}
+			}
+			o.tiles = append(o.tiles, objTile{idx: idx, x: tx, col: col})
 		}
+	}
 
-		// next
-		i = i - 1
-		if i == -1 {
-			i = 127
-		}
+	o.record(y)
+}
 
-		// all done
-		if i == lowest {
-			break
+// Record OBJs which have fetched tiles on the line (from the highest priority one)
+func (o *objl) record(y uint16) {
+	l := &o.shown[y-1]
+	l.n = 0
+	for i := len(o.tiles) - 1; i >= 0; i-- {
+		idx := uint8(o.tiles[i].idx)
+		if l.n == 0 || l.objs[l.n-1] != idx {
+			l.objs[l.n] = idx
+			l.n++
 		}
 	}
 }
 
-// Draw an obj at `row=y`
-func (o *objl) drawObjScanline(i int, buf []iro.RGB555, y uint16, start, end int) {
+func (o *objl) inRange(idx int, y uint16) bool {
+	oam := o.r.oam
+	obj := &oam.objs[idx]
+	width, height := oam.size[0], oam.size[0]
+	if obj.large {
+		width, height = oam.size[1], oam.size[1]
+	}
+	if o.r.objInterlace {
+		height >>= 1 // OBJs are displayed in half height
+	}
+
+	// OBJs at X=257..511 are off screen unless they wrap to the left edge
+	if obj.x > 256 && int(obj.x)+width-1 < 512 {
+		return false
+	}
+	return int((y-uint16(obj.y))&0xFF) < height // Y wraps around at 256
+}
+
+// When sprites overlap, the sprite which comes first in OAM wins regardless of its priority
+//
+// So tiles are drawn from the last sprite, and the first one overwrites others.
+func (o *objl) drawScanline(l *scanline, y uint16, start, end int) {
+	for _, t := range o.tiles {
+		o.drawObjTile(t, l, y, start, end)
+	}
+}
+
+// Draw 8px of an obj at `row=y`
+func (o *objl) drawObjTile(t objTile, l *scanline, y uint16, start, end int) {
 	oam := o.r.oam
-	obj := &oam.objs[i]
+	obj := &oam.objs[t.idx]
+
+	id := LAYER_OBJ
+	if obj.palID < 4 {
+		id = LAYER_OBJ_NOMATH
+	}
 
 	pal := o.r.pal.buf[0x80:]
 	pal = pal[obj.palID*16 : (obj.palID+1)*16]
@@ -321,42 +501,32 @@ func (o *objl) drawObjScanline(i int, buf []iro.RGB555, y uint16, start, end int
 		baseAddr += 0x1000 + oam.gap
 	}
 
-	tiledata := o.r.vram.buf[baseAddr:]
-
 	width, height := oam.size[0], oam.size[0]
 	if obj.large {
 		width, height = oam.size[1], oam.size[1]
 	}
 
-	row := y - uint16(obj.y) // (スプライトの一番上を0行目として)上から何行目か
+	row := (y - uint16(obj.y)) & 0xFF // (スプライトの一番上を0行目として)上から何行目か
+	if o.r.objInterlace {
+		row = row<<1 | btou16(o.r.field)
+	}
 	if obj.vflip {
 		row = uint16(height-1) - row
 	}
 	tilerow := row / 8                                                                   // 上から何タイル目か
 	tileID := (uint16(obj.tile) & 0xFF00) | ((uint16(obj.tile) + 16*(tilerow)) & 0x00FF) // NOTE: OBJタイルの3桁目(Hex)は桁上がりしない
 
-	for x := 0; x < width; x++ {
-		if x&0b111 == 0 {
-			col := uint16(x / 8)                                    // 左から何タイル目か
-			tileID := (tileID & 0xFFF0) | ((tileID + col) & 0x000F) // NOTE: OBJタイルの2,3桁目(Hex)は桁上がりしない
-			tile := tiledata[16*(tileID):]
-			flipedY := flip(8, obj.vflip, int(y-uint16(obj.y)))
-			rowdata := [2]uint16{tile[flipedY&0b111], tile[flipedY&0b111+8]}
-			planes := [4]uint8{
-				uint8(rowdata[0]), uint8(rowdata[0] >> 8),
-				uint8(rowdata[1]), uint8(rowdata[1] >> 8),
-			}
-
-			// 1pxずつ描画
-			for i := 0; i < 8; i++ {
-				colorID := uint8(0)
-				for j := 0; j < 4; j++ {
-					colorID += ((planes[j] >> (7 - i)) & 0b1) << j
-				}
-				if colorID != 0 {
-					buf[(int(obj.x)+flip(width, obj.hflip, x+i))%512] = pal[colorID]
-				}
-			}
+	col := uint16(flip(width/8, obj.hflip, t.col))
+	tileID = (tileID & 0xFFF0) | ((tileID + col) & 0x000F) // NOTE: OBJタイルの2,3桁目(Hex)は桁上がりしない
+	tileStart := (baseAddr + 16*uint(tileID)) & 0x7FFF
+	pixels := o.r.vram.tileRow(COLOR_4BPP, tileStart, int(row&0b111))
+
+	// 1pxずつ描画
+	for i := 0; i < 8; i++ {
+		colorID := pixels[i]
+		px := t.x + flip(8, obj.hflip, i)
+		if colorID != 0 && px >= start && px < end {
+			l.put(px, pal[colorID], id, obj.prio)
 		}
 	}
 }
@@ -0,0 +1,120 @@
+package core
+
+import "github.com/pokemium/iro"
+
+// CGWSEL.4-7
+type mathRegion uint8
+
+const (
+	REGION_NEVER   mathRegion = iota
+	REGION_OUTSIDE            // outside of color window
+	REGION_INSIDE             // inside of color window
+	REGION_ALWAYS
+)
+
+// Color math (CGWSEL, CGADSUB, COLDATA)
+type colorMath struct {
+	clip     mathRegion // CGWSEL.6-7 (Force main screen black)
+	prevent  mathRegion // CGWSEL.4-5 (Prevent color math)
+	addSub   bool       // CGWSEL.1 (0: fixed color, 1: sub screen)
+	direct   bool       // CGWSEL.0 (Direct color for 256-color BGs)
+	subtract bool       // CGADSUB.7
+	half     bool       // CGADSUB.6
+	enable   [6]bool    // CGADSUB.0-5 (BG1, BG2, BG3, BG4, OBJ(Palette 4..7), Backdrop)
+	fixed    iro.RGB555 // COLDATA
+}
+
+func (m *colorMath) setFixedColor(val uint8) {
+	intensity := iro.RGB555(val & 0b1_1111)
+	c := m.fixed
+	if bit(val, 5) {
+		c = (c & 0x7FE0) | intensity
+	}
+	if bit(val, 6) {
+		c = (c & 0x7C1F) | (intensity << 5)
+	}
+	if bit(val, 7) {
+		c = (c & 0x03FF) | (intensity << 10)
+	}
+	m.fixed = c
+}
+
+// Direct color(CGWSEL.0): 8bpp pixel value is BBGGGRRR and palette bits of tilemap is bgr
+//
+//	R: RRRr0, G: GGGg0, B: BBb00
+func directColor(pal, c uint8) iro.RGB555 {
+	r := uint16(c&0b111)<<2 | uint16(pal&0b001)<<1
+	g := uint16((c>>3)&0b111)<<2 | uint16(pal&0b010)
+	b := uint16(c>>6)<<3 | uint16(pal&0b100)
+	return iro.RGB555(r | g<<5 | b<<10)
+}
+
+func (r mathRegion) in(window bool) bool {
+	switch r {
+	case REGION_OUTSIDE:
+		return !window
+	case REGION_INSIDE:
+		return window
+	case REGION_ALWAYS:
+		return true
+	}
+	return false
+}
+
+// Blend main and sub screen into dst.
+//
+// window represents whether each pixel is inside the color window.
+func (m *colorMath) blend(dst, main, sub []iro.RGB555, mainsrc, subsrc []layerID, window []bool) {
+	for x := range dst {
+		c := main[x]
+		inside := window[x]
+
+		clipped := m.clip.in(inside)
+		if clipped {
+			c = 0x0000
+		}
+
+		src := mainsrc[x]
+		if m.prevent.in(inside) || src == LAYER_OBJ_NOMATH || !m.enable[src] {
+			dst[x] = c
+			continue
+		}
+
+		operand, half := m.fixed, m.half && !clipped
+		if m.addSub {
+			if subsrc[x] == LAYER_BACKDROP {
+				// transparent sub screen is fixed color and isn't halved
+				half = false
+			} else {
+				operand = sub[x]
+			}
+		}
+
+		dst[x] = m.calc(c, operand, half)
+	}
+}
+
+func (m *colorMath) calc(a, b iro.RGB555, half bool) iro.RGB555 {
+	result := iro.RGB555(0)
+	for i := 0; i < 3; i++ {
+		shift := 5 * i
+		x, y := int((a>>shift)&0x1F), int((b>>shift)&0x1F)
+
+		c := x + y
+		if m.subtract {
+			c = x - y
+		}
+		if half {
+			c >>= 1
+		}
+
+		switch {
+		case c < 0:
+			c = 0
+		case c > 0x1F:
+			c = 0x1F
+		}
+		result |= iro.RGB555(c) << shift
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+package core
+
+import "testing"
+
+// PPU after reset with full brightness
+func newTestPpu() *ppu {
+	s := New().(*sfc)
+	p := s.ppu
+	p.reset()
+	p.writeIO(0x00, 0x0F) // INIDISP
+	return p
+}
+
+func TestColorMath(t *testing.T) {
+	p := newTestPpu()
+	p.pal.buf[0] = 0x0010 // backdrop: red 16
+	p.writeIO(0x32, 0x28) // COLDATA: red 8
+
+	tests := []struct {
+		name            string
+		cgwsel, cgadsub uint8
+		want            uint16
+	}{
+		{"off", 0x00, 0x00, 16},
+		{"add", 0x00, 0x20, 24},
+		{"sub", 0x00, 0xA0, 8},
+		{"half add", 0x00, 0x60, 12},
+		{"half sub", 0x00, 0xE0, 4},
+		{"clip always", 0xC0, 0x20, 8}, // main is black, fixed color is added
+		{"prevent always", 0x30, 0x20, 16},
+	}
+	for _, tt := range tests {
+		p.writeIO(0x30, tt.cgwsel)
+		p.writeIO(0x31, tt.cgadsub)
+		p.r.drawScanline(1)
+		if got := uint16(p.r.frame[0]); got != tt.want {
+			t.Errorf("%s: %04X, want %04X", tt.name, got, tt.want)
+		}
+	}
+
+	// saturation
+	p.writeIO(0x30, 0x00)
+	p.writeIO(0x31, 0x20)
+	p.writeIO(0x32, 0x3F)
+	p.r.drawScanline(1)
+	if got := p.r.frame[0]; got != 0x001F {
+		t.Errorf("add is not saturated: %v", got)
+	}
+}
+
+func TestDirectColor(t *testing.T) {
+	p := newTestPpu()
+	p.writeIO(0x05, 0x03) // BGMODE
+	p.writeIO(0x07, 0x00) // BG1SC: tilemap at 0
+	p.writeIO(0x0B, 0x01) // BG12NBA: BG1 tiles at word 0x1000
+	p.writeIO(0x2C, 0x01) // TM: BG1
+	for i := 0; i < 32; i++ {
+		p.vram.buf[0x1000+32+i] = 0xFFFF // 8bpp tile 1: color 0xFF
+	}
+	p.vram.buf[0] = 0x1C01 // palette 7, tile 1
+	p.pal.buf[0xFF] = 0x1234
+
+	p.r.drawScanline(1)
+	if got := p.r.frame[0]; got != 0x1234 {
+		t.Errorf("CGRAM: %v", got)
+	}
+	p.writeIO(0x30, 0x01) // CGWSEL: direct color
+	p.r.drawScanline(1)
+	if got := p.r.frame[0]; got != 0x73DE {
+		t.Errorf("direct: %v", got)
+	}
+
+	// Mode 7 has no palette bits
+	p = newMode7Test()
+	p.writeIO(0x30, 0x01)
+	p.r.drawScanline(1)
+	if got := p.r.frame[0]; got != 5<<2 {
+		t.Errorf("Mode 7: %v", got)
+	}
+
+	for _, tt := range []struct {
+		pal, c uint8
+		want   uint16
+	}{
+		{0b001, 0b00_000_001, 0b00110},
+		{0b010, 0b00_001_000, 0b00110 << 5},
+		{0b100, 0b01_000_000, 0b01100 << 10},
+	} {
+		if got := directColor(tt.pal, tt.c); uint16(got) != tt.want {
+			t.Errorf("directColor(%03b, %08b) = %04X, want %04X", tt.pal, tt.c, uint16(got), tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package core
+
+// Mode 7 registers (M7SEL, M7A..M7D, M7X, M7Y, M7HOFS, M7VOFS)
+type mode7 struct {
+	a, b, c, d   int16 // M7A..M7D, 1.7.8 fixed point
+	x, y         int16 // M7X, M7Y (Center, 13bit signed)
+	hofs, vofs   int16 // M7HOFS, M7VOFS (13bit signed)
+	over         uint8 // M7SEL.6-7 (Screen over)
+	hflip, vflip bool  // M7SEL.0, M7SEL.1
+	latch        uint8 // M7 registers are write-twice and share the previous written value
+}
+
+const (
+	M7_WRAP        = 0 // 0 and 1 both wrap within 1024x1024
+	M7_TRANSPARENT = 2
+	M7_TILE0       = 3
+)
+
+// write-twice 16bit value for M7A..M7D, M7X, M7Y, M7HOFS and M7VOFS
+func (m *mode7) write(val uint8) uint16 {
+	result := uint16(val)<<8 | uint16(m.latch)
+	m.latch = val
+	return result
+}
+
+// Signed 16bit M7A * Signed 8bit M7B(upper byte)
+func (m *mode7) mul() uint24 {
+	return toU24(uint32(int32(m.a) * int32(int8(m.b>>8))))
+}
+
+func sext13(val uint16) int16 {
+	return int16(val<<3) >> 3
+}
+
+// Clip 13bit signed value into 10bit signed value
+func m7clip(n int) int {
+	if n&0x2000 != 0 {
+		return n | ^1023
+	}
+	return n & 1023
+}
+
+// Map position of the screen pixel (0, y) in 1024x1024 Mode7 map (8bit fraction, not wrapped)
+func (m *mode7) origin(y int) (int, int) {
+	if m.vflip {
+		y = 255 - y
+	}
+
+	a, b, c, d := int(m.a), int(m.b), int(m.c), int(m.d)
+	cx, cy := int(m.x), int(m.y)
+	hofs, vofs := int(m.hofs), int(m.vofs)
+
+	originX := (a * m7clip(hofs-cx) &^ 63) + (b * m7clip(vofs-cy) &^ 63) + (b * y &^ 63) + (cx << 8)
+	originY := (c * m7clip(hofs-cx) &^ 63) + (d * m7clip(vofs-cy) &^ 63) + (d * y &^ 63) + (cy << 8)
+	return originX, originY
+}
+
+// Map position of the screen pixel (x, y) in 1024x1024 Mode7 map (not wrapped)
+func (m *mode7) transform(x, y int) (int, int) {
+	if m.hflip {
+		x = 255 - x
+	}
+	originX, originY := m.origin(y)
+	return (originX + int(m.a)*x) >> 8, (originY + int(m.c)*x) >> 8
+}
+
+// Mode7 BG1(8bpp) and EXTBG BG2(7bpp + priority bit)
+func (b *bg) drawMode7Scanline(l *scanline, y uint16, start, end int) {
+	m := &b.r.m7
+	vram := b.r.vram.buf
+	id := b.id()
+
+	a, c := int(m.a), int(m.c)
+	originX, originY := m.origin(int(y))
+
+	for x := start; x < end; x++ {
+		xx := x
+		if m.hflip {
+			xx = 255 - xx
+		}
+
+		px, py := (originX+a*xx)>>8, (originY+c*xx)>>8
+		outside := (px|py)&^1023 != 0
+
+		// 128x128 tilemap(lower byte of VRAM), 8x8 tile is 64 bytes(upper byte of VRAM)
+		tile := vram[((py>>3)&127)*128+((px>>3)&127)] & 0xFF
+		if outside && m.over == M7_TILE0 {
+			tile = 0
+		}
+		colorID := uint8(vram[(int(tile)<<6)|((py&7)<<3)|(px&7)] >> 8)
+		if outside && m.over == M7_TRANSPARENT {
+			colorID = 0
+		}
+
+		prio := uint8(0)
+		if b.index == 2 {
+			// EXTBG: bit7 is priority
+			prio = colorID >> 7
+			colorID &= 0x7F
+		}
+
+		if colorID != 0 {
+			c := b.r.pal.buf[colorID]
+			if b.r.math.direct && b.index == 1 {
+				c = directColor(0, colorID) // EXTBG BG2 doesn't use direct color
+			}
+			l.put(x, c, id, prio)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package core
+
+import "testing"
+
+func write16(p *ppu, addr uint, val uint16) {
+	p.writeIO(addr, uint8(val))
+	p.writeIO(addr, uint8(val>>8))
+}
+
+// Mode 7 BG1 with identity matrix, only tile(0, 0) is tile 1 filled with color 5 (0x1234), tile 0 is color 6 (0x4321)
+func newMode7Test() *ppu {
+	p := newTestPpu()
+	p.writeIO(0x05, 0x07) // BGMODE
+	p.writeIO(0x2C, 0x01) // TM: BG1
+	write16(p, 0x1B, 0x0100)
+	write16(p, 0x1E, 0x0100)
+	p.vram.buf[0] = 0x0001
+	for i := 0; i < 64; i++ {
+		p.vram.buf[i] |= 6 << 8
+		p.vram.buf[64+i] |= 5 << 8
+	}
+	p.pal.buf[5], p.pal.buf[6] = 0x1234, 0x4321
+	return p
+}
+
+func TestMode7(t *testing.T) {
+	p := newMode7Test()
+	check := func(name string, want ...uint16) {
+		t.Helper()
+		p.r.drawScanline(1)
+		for x, c := range want {
+			if got := uint16(p.r.frame[x*4]); got != c {
+				t.Errorf("%s: x=%d %04X, want %04X", name, x*4, got, c)
+			}
+		}
+	}
+	check("identity", 0x1234, 0x1234, 0x4321)
+
+	write16(p, 0x0D, 1024) // M7HOFS
+	check("wrap", 0x1234, 0x1234, 0x4321)
+
+	write16(p, 0x0D, 0x1FF8) // -8
+	check("wrap negative", 0x4321, 0x4321, 0x1234, 0x1234, 0x4321)
+	p.writeIO(0x1A, 0x80) // M7SEL: transparent outside
+	check("transparent", 0, 0, 0x1234, 0x1234, 0x4321)
+	p.writeIO(0x1A, 0xC0) // M7SEL: tile 0 outside
+	check("tile 0", 0x4321, 0x4321, 0x1234, 0x1234, 0x4321)
+
+	write16(p, 0x0D, 0)
+	p.writeIO(0x1A, 0x00)
+	write16(p, 0x1B, 0x0080) // M7A: 0.5 (zoom in)
+	check("scale", 0x1234, 0x1234, 0x1234, 0x1234, 0x4321)
+
+	write16(p, 0x1B, 0x0100)
+	p.writeIO(0x1A, 0x01) // M7SEL: H-flip, screen x 0 is map x 255
+	check("hflip", 0x4321, 0x4321, 0x4321)
+	if p.r.frame[255] != 0x1234 || p.r.frame[248] != 0x1234 || p.r.frame[247] != 0x4321 {
+		t.Errorf("hflip: %v %v %v", p.r.frame[255], p.r.frame[248], p.r.frame[247])
+	}
+}
+
+func TestMode7Multiply(t *testing.T) {
+	p := newTestPpu()
+	write16(p, 0x1B, 0xFFFF) // M7A: -1
+	p.writeIO(0x1C, 0x05)    // M7B: 5 (upper byte)
+	mpy := uint32(p.readIO(0x34, 0)) | uint32(p.readIO(0x35, 0))<<8 | uint32(p.readIO(0x36, 0))<<16
+	if mpy != 0xFFFFFB {
+		t.Fatalf("MPY: %06X", mpy)
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/pokemium/iro"
+)
+
+// Mode 0 BG1, color of the pixel (x, y) in each tile is (x+y)%3+1
+func newMosaicTest() *ppu {
+	p := newTestPpu()
+	p.writeIO(0x05, 0x00) // BGMODE
+	p.writeIO(0x07, 0x00) // BG1SC: tilemap at 0
+	p.writeIO(0x0B, 0x01) // BG12NBA: BG1 tiles at word 0x1000
+	p.writeIO(0x2C, 0x01) // TM: BG1
+	for y := 0; y < 8; y++ {
+		row := uint16(0)
+		for x := 0; x < 8; x++ {
+			c := uint16((x+y)%3 + 1)
+			row |= (c&1)<<(7-x) | (c>>1)<<(15-x)
+		}
+		p.vram.buf[0x1000+8+y] = row // tile 1
+	}
+	for i := 0; i < 32*32; i++ {
+		p.vram.buf[i] = 0x0001
+	}
+	p.pal.buf[1], p.pal.buf[2], p.pal.buf[3] = 0x001F, 0x03E0, 0x7C00
+	return p
+}
+
+// Draw lines 1..n and return the frame
+func drawLines(p *ppu, n uint16) []iro.RGB555 {
+	for y := uint16(1); y <= n; y++ {
+		p.r.mosaic.scanline(y)
+		p.r.drawScanline(y)
+	}
+	return append([]iro.RGB555{}, p.r.frame[:HORIZONTAL*int(n)]...)
+}
+
+func TestMosaic(t *testing.T) {
+	p := newMosaicTest()
+	plain := drawLines(p, 8)
+
+	p.writeIO(0x06, 0x31) // MOSAIC: 4x4 on BG1
+	got := drawLines(p, 8)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			want := plain[(y-y%4)*HORIZONTAL+x-x%4]
+			if c := got[y*HORIZONTAL+x]; c != want {
+				t.Fatalf("(%d, %d): %v, want %v", x, y+1, c, want)
+			}
+		}
+	}
+
+	// only BG2 is mosaic
+	p.writeIO(0x06, 0x32)
+	if got := drawLines(p, 8); !equalFrame(got, plain) {
+		t.Fatal("mosaic is applied to BG1 disabled in MOSAIC")
+	}
+}
+
+func equalFrame(a, b []iro.RGB555) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
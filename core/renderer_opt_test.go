@@ -0,0 +1,48 @@
+package core
+
+import "testing"
+
+func TestOffsetPerTile(t *testing.T) {
+	p := newTestPpu()
+	p.writeIO(0x05, 0x02) // BGMODE
+	p.writeIO(0x07, 0x00) // BG1SC: tilemap at 0
+	p.writeIO(0x09, 0x04) // BG3SC: tilemap at word 0x400
+	p.writeIO(0x0B, 0x01) // BG12NBA: BG1 tiles at word 0x1000
+	p.writeIO(0x2C, 0x01) // TM: BG1
+	for i := 0; i < 8; i++ {
+		p.vram.buf[0x1000+16+i] = 0x00FF // 4bpp tile 1: color 1
+		p.vram.buf[0x1000+32+i] = 0x00FF // 8bpp tile 1: color 1
+	}
+	p.pal.buf[1] = 0x001F
+
+	// BG1 tile 1 is at column 4 of row 0 and column 1 of row 2
+	p.vram.buf[4] = 0x0001
+	p.vram.buf[2*32+1] = 0x0001
+	opt := p.vram.buf[0x400:]
+
+	shown := func(x int) bool {
+		return p.r.frame[x] == 0x001F
+	}
+	for _, tt := range []struct {
+		name       string
+		mode       uint8
+		hofs, vofs uint16  // BG3 tilemap of column 0
+		want       [3]bool // column 0, 1 and 2 of the screen
+	}{
+		{"none", 2, 0, 0, [3]bool{false, false, false}},
+		{"H", 2, 0x2000 | 24, 0, [3]bool{false, true, false}},
+		{"H for BG2", 2, 0x4000 | 24, 0, [3]bool{false, false, false}},
+		{"V", 2, 0, 0x2000 | 16, [3]bool{false, true, false}},
+		{"mode 4 H", 4, 0x2000 | 24, 0, [3]bool{false, true, false}},
+		{"mode 4 V", 4, 0xA000 | 16, 0, [3]bool{false, true, false}},
+	} {
+		p.writeIO(0x05, tt.mode)
+		opt[0], opt[32] = tt.hofs, tt.vofs
+		p.r.drawScanline(1)
+		for col, want := range tt.want {
+			if got := shown(col * 8); got != want {
+				t.Errorf("%s: column %d is shown: %v", tt.name, col, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package core
+
+import "testing"
+
+// Mode 1 BG1 (color 1 = 0x0001) and OBJ #0, #1 at (0, 0), BG1 tile is transparent unless tilemap[0] is set
+func newPriorityTest() *ppu {
+	p := newTestPpu()
+	p.writeIO(0x05, 0x01) // BGMODE
+	p.writeIO(0x07, 0x00) // BG1SC: tilemap at 0
+	p.writeIO(0x0B, 0x01) // BG12NBA: BG1 tiles at word 0x1000
+	p.writeIO(0x01, 0x02) // OBSEL: OBJ tiles at word 0x4000
+	p.writeIO(0x2C, 0x11) // TM: BG1, OBJ
+	for i := 0; i < 8; i++ {
+		p.vram.buf[0x1000+16+i] = 0x00FF // BG1 tile 1: color 1
+		p.vram.buf[0x4000+i] = 0xFF00    // OBJ tile 0: color 2
+	}
+	p.pal.buf[1] = 0x0001
+	p.pal.buf[0x82], p.pal.buf[0x92] = 0x0002, 0x0003 // OBJ palette 0, 1
+	for i := range p.oam.objs {
+		p.oam.objs[i].y = 240
+	}
+	p.oam.objs[0].y = 0
+	return p
+}
+
+func TestLayerPriority(t *testing.T) {
+	p := newPriorityTest()
+	for _, tt := range []struct {
+		name    string
+		entry   uint16 // BG1 tilemap[0]
+		objPrio uint8
+		want    uint16
+	}{
+		{"OBJ over backdrop", 0x0000, 0, 0x0002},
+		{"BG1 low over OBJ 1", 0x0001, 1, 0x0001},
+		{"OBJ 2 over BG1 low", 0x0001, 2, 0x0002},
+		{"BG1 high over OBJ 2", 0x2001, 2, 0x0001},
+		{"OBJ 3 over BG1 high", 0x2001, 3, 0x0002},
+	} {
+		p.vram.buf[0] = tt.entry
+		p.oam.objs[0].prio = tt.objPrio
+		p.r.drawScanline(1)
+		if got := uint16(p.r.frame[0]); got != tt.want {
+			t.Errorf("%s: %04X, want %04X", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSpritePriority(t *testing.T) {
+	p := newPriorityTest()
+
+	// OBJ #0 (priority 0) hides OBJ #1 (priority 3) even though BG1 covers OBJ #0
+	p.vram.buf[0] = 0x0001
+	o := &p.oam.objs[1]
+	o.y, o.prio, o.palID = 0, 3, 1
+	p.r.drawScanline(1)
+	if got := p.r.frame[0]; got != 0x0001 {
+		t.Errorf("OBJ #1 is shown through OBJ #0: %v", got)
+	}
+
+	p.writeIO(0x2C, 0x10) // TM: OBJ
+	p.r.drawScanline(1)
+	if got := p.r.frame[0]; got != 0x0002 {
+		t.Errorf("OBJ #0 isn't in front: %v", got)
+	}
+}
+
+func TestBG3Priority(t *testing.T) {
+	p := newPriorityTest()
+	p.writeIO(0x09, 0x04) // BG3SC: tilemap at word 0x400
+	p.writeIO(0x0C, 0x01) // BG34NBA: BG3 tiles at word 0x1000
+	p.writeIO(0x2C, 0x15) // TM: BG1, BG3, OBJ
+	p.vram.buf[0] = 0x2001
+	p.vram.buf[0x400] = 0x2402 // high priority, palette 1, tile 2 (color 1)
+	p.pal.buf[5] = 0x0005
+	p.oam.objs[0].prio = 3
+
+	p.r.drawScanline(1)
+	if got := p.r.frame[0]; got != 0x0002 {
+		t.Errorf("BG3 high is over OBJ 3: %v", got)
+	}
+
+	p.writeIO(0x05, 0x09) // BGMODE: mode 1, BG3 high priority is the front
+	p.r.drawScanline(1)
+	if got := p.r.frame[0]; got != 0x0005 {
+		t.Errorf("BG3 high isn't the front: %v", got)
+	}
+}
@@ -0,0 +1,83 @@
+package core
+
+import "testing"
+
+// n OBJs on line 1 at x = 0, 8, 16, ...
+func newSpriteTest(n int, large bool) *ppu {
+	p := newTestPpu()
+	p.writeIO(0x01, 0x02) // OBSEL: 8x8 and 16x16, tiles at word 0x4000
+	p.writeIO(0x2C, 0x10) // TM: OBJ
+	for _, tile := range []int{0, 1} {
+		for i := 0; i < 8; i++ {
+			p.vram.buf[0x4000+tile*16+i] = 0xFF00 // color 2
+		}
+	}
+	p.pal.buf[0x82] = 0x0002
+	for i := range p.oam.objs {
+		o := &p.oam.objs[i]
+		o.y = 240
+		if i < n {
+			o.y, o.large = 0, large
+			o.x = uint16(i * 8)
+		}
+	}
+	return p
+}
+
+func TestSpriteRangeOver(t *testing.T) {
+	for _, tt := range []struct {
+		n    int
+		stat uint8
+	}{
+		{32, 0x00},
+		{33, 0x40},
+	} {
+		p := newSpriteTest(tt.n, false)
+		p.r.drawScanline(1)
+		if stat := p.readIO(0x3E, 0) & 0xC0; stat != tt.stat {
+			t.Errorf("%d OBJs: STAT77 %02X, want %02X", tt.n, stat, tt.stat)
+		}
+
+		// OBJ #32 at x=256 is off screen anyway, so check OBJ #31 is shown
+		if c := p.r.frame[31*8]; c != 0x0002 {
+			t.Errorf("%d OBJs: OBJ #31 isn't shown", tt.n)
+		}
+	}
+}
+
+func TestSpriteTimeOver(t *testing.T) {
+	// 17 16x16 OBJs are 34 tiles
+	p := newSpriteTest(17, true)
+	p.r.drawScanline(1)
+	if stat := p.readIO(0x3E, 0) & 0xC0; stat != 0x00 {
+		t.Fatalf("STAT77 %02X", stat)
+	}
+
+	// tiles are fetched from the last OBJ, so OBJ #0 is dropped
+	p = newSpriteTest(18, true)
+	p.r.drawScanline(1)
+	if stat := p.readIO(0x3E, 0) & 0xC0; stat != 0x80 {
+		t.Fatalf("STAT77 %02X", stat)
+	}
+	if p.r.frame[0] != 0 || p.r.frame[8] != 0x0002 {
+		t.Fatalf("OBJ #0: %v, OBJ #1: %v", p.r.frame[0], p.r.frame[8])
+	}
+
+	p.c.SpriteLimit(false)
+	p.r.drawScanline(1)
+	if p.r.frame[0] != 0x0002 {
+		t.Fatal("OBJ #0 is dropped without sprite limits")
+	}
+}
+
+func TestSpritePriorityRotation(t *testing.T) {
+	p := newSpriteTest(33, false)
+	p.writeIO(0x02, 0x02) // OAMADD: OBJ #1
+	p.writeIO(0x03, 0x80) // priority rotation
+
+	// OBJ #1..#32 are taken, so OBJ #0 is the 33rd
+	p.r.drawScanline(1)
+	if p.r.frame[0] != 0 || p.r.frame[8] != 0x0002 {
+		t.Fatalf("OBJ #0: %v, OBJ #1: %v", p.r.frame[0], p.r.frame[8])
+	}
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/pokemium/iro"
+)
+
+func TestBrightness(t *testing.T) {
+	p := newTestPpu()
+	p.pal.buf[0] = 0x7FFF
+	for _, tt := range []struct {
+		inidisp uint8
+		want    uint16
+	}{
+		{0x0F, 31},
+		{0x07, 15}, // 31 * 8/16
+		{0x01, 3},
+		{0x00, 0},
+	} {
+		p.writeIO(0x00, tt.inidisp)
+		p.r.drawScanline(1)
+		if got, want := p.r.frame[0], iro.RGB555(tt.want|tt.want<<5|tt.want<<10); got != want {
+			t.Errorf("INIDISP %02X: %v, want %v", tt.inidisp, got, want)
+		}
+	}
+}
+
+func TestForcedBlank(t *testing.T) {
+	p := newTestPpu()
+	p.pal.buf[0] = 0x7FFF
+	p.vcount = 1
+	p.startHBlank()
+
+	// line 2 is drawn in forced blank
+	p.writeIO(0x00, 0x8F)
+	p.vcount = 2
+	p.startHBlank()
+	if p.r.frame[0] != 0x7FFF || p.r.frame[HORIZONTAL] != 0 {
+		t.Fatalf("line 1: %v, line 2: %v", p.r.frame[0], p.r.frame[HORIZONTAL])
+	}
+}
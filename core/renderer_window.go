@@ -9,21 +9,38 @@ const (
 	XNOR
 )
 
+// Index of window mask settings(WxxSEL, WBGLOG, WOBJLOG)
+//
+// WINDOW_BG1..WINDOW_OBJ is the same as LAYER_BG1..LAYER_OBJ
+const (
+	WINDOW_BG1 = iota
+	WINDOW_BG2
+	WINDOW_BG3
+	WINDOW_BG4
+	WINDOW_OBJ
+	WINDOW_COLOR
+)
+
 type windowSystem struct {
 	win1, win2 window
 	logic      [6]maskLogic // WBGLOG/WOBJLOG
+	tmw, tsw   [5]bool      // TMW/TSW (BG1, BG2, BG3, BG4, OBJ)
+
+	// Window masks of current line (WINDOW_BG1..WINDOW_COLOR)
+	masks [6][HORIZONTAL]bool
+	dirty bool // WxxSEL, WHx or WxxLOG was written after masks were calculated
 }
 
 // WxxSEL
 type maskSetting struct {
-	enable bool // bit0
-	invert bool // bit1
+	invert bool // bit0
+	enable bool // bit1
 }
 
 func windowMask(val uint8) maskSetting {
 	return maskSetting{
-		enable: bit(val, 0),
-		invert: bit(val, 1),
+		invert: bit(val, 0),
+		enable: bit(val, 1),
 	}
 }
 
@@ -31,3 +48,61 @@ type window struct {
 	left, right uint8
 	mask        [6]maskSetting // WxxSEL
 }
+
+// Check whether x is inside of the window (WHx)
+func (w *window) inside(i, x int) bool {
+	in := int(w.left) <= x && x <= int(w.right)
+	return in != w.mask[i].invert
+}
+
+// Check whether x is masked by the windows of i(WINDOW_BG1..WINDOW_COLOR)
+func (w *windowSystem) inside(i, x int) bool {
+	en1, en2 := w.win1.mask[i].enable, w.win2.mask[i].enable
+
+	switch {
+	case en1 && en2:
+		in1, in2 := w.win1.inside(i, x), w.win2.inside(i, x)
+		switch w.logic[i] {
+		case OR:
+			return in1 || in2
+		case AND:
+			return in1 && in2
+		case XOR:
+			return in1 != in2
+		case XNOR:
+			return in1 == in2
+		}
+	case en1:
+		return w.win1.inside(i, x)
+	case en2:
+		return w.win2.inside(i, x)
+	}
+
+	return false
+}
+
+// Calculate window masks of current line, only when window registers were written
+func (w *windowSystem) update() {
+	if !w.dirty {
+		return
+	}
+	w.dirty = false
+	for i := range w.masks {
+		for x := range w.masks[i] {
+			w.masks[i][x] = w.inside(i, x)
+		}
+	}
+}
+
+// Window mask of the layer on main(or sub) screen, nil if window is disabled by TMW(or TSW)
+func (w *windowSystem) mask(id layerID, sub bool) []bool {
+	enable := w.tmw
+	if sub {
+		enable = w.tsw
+	}
+
+	if int(id) >= len(enable) || !enable[id] {
+		return nil
+	}
+	return w.masks[id][:]
+}
@@ -0,0 +1,85 @@
+package core
+
+import "testing"
+
+// Mode 0 BG1 filled with color 1 (0x001F) on the main screen
+func newWindowTest() *ppu {
+	p := newTestPpu()
+	p.writeIO(0x05, 0x00) // BGMODE
+	p.writeIO(0x07, 0x00) // BG1SC: tilemap at 0
+	p.writeIO(0x0B, 0x01) // BG12NBA: BG1 tiles at word 0x1000
+	p.writeIO(0x2C, 0x01) // TM: BG1
+	for i := 0; i < 8; i++ {
+		p.vram.buf[0x1000+8+i] = 0x00FF // tile 1
+	}
+	for i := 0; i < 32*32; i++ {
+		p.vram.buf[i] = 0x0001
+	}
+	p.pal.buf[1] = 0x001F
+	p.writeIO(0x26, 10) // WH0
+	p.writeIO(0x27, 20) // WH1
+	p.writeIO(0x28, 15) // WH2
+	p.writeIO(0x29, 30) // WH3
+	return p
+}
+
+// x in [from, to] must be masked and others not
+func checkMasked(t *testing.T, p *ppu, name string, ranges ...[2]int) {
+	t.Helper()
+	p.r.drawScanline(1)
+	for x := 0; x < HORIZONTAL; x++ {
+		masked := false
+		for _, r := range ranges {
+			masked = masked || (r[0] <= x && x <= r[1])
+		}
+		if got := p.r.frame[x] == 0; got != masked {
+			t.Errorf("%s: x=%d is masked: %v", name, x, got)
+			return
+		}
+	}
+}
+
+func TestWindowMask(t *testing.T) {
+	p := newWindowTest()
+	checkMasked(t, p, "no window")
+
+	p.writeIO(0x23, 0x02) // W12SEL: BG1 window 1
+	checkMasked(t, p, "TMW disabled")
+
+	p.writeIO(0x2E, 0x01) // TMW: BG1
+	checkMasked(t, p, "window 1", [2]int{10, 20})
+
+	p.writeIO(0x23, 0x03) // inverted
+	checkMasked(t, p, "invert", [2]int{0, 9}, [2]int{21, 255})
+
+	p.writeIO(0x23, 0x0A) // window 1 and 2
+	for _, tt := range []struct {
+		logic  uint8
+		name   string
+		ranges [][2]int
+	}{
+		{0, "OR", [][2]int{{10, 30}}},
+		{1, "AND", [][2]int{{15, 20}}},
+		{2, "XOR", [][2]int{{10, 14}, {21, 30}}},
+		{3, "XNOR", [][2]int{{0, 9}, {15, 20}, {31, 255}}},
+	} {
+		p.writeIO(0x2A, tt.logic) // WBGLOG
+		checkMasked(t, p, tt.name, tt.ranges...)
+	}
+}
+
+func TestColorWindow(t *testing.T) {
+	p := newWindowTest()
+	p.writeIO(0x25, 0x20) // WOBJSEL: color window 1
+	p.writeIO(0x30, 0x80) // CGWSEL: main screen is black inside of color window
+	checkMasked(t, p, "clip inside", [2]int{10, 20})
+
+	p.writeIO(0x30, 0x40) // outside
+	checkMasked(t, p, "clip outside", [2]int{0, 9}, [2]int{21, 255})
+
+	// color math is prevented inside of color window
+	p.writeIO(0x30, 0x20)
+	p.writeIO(0x31, 0x81) // CGADSUB: subtract on BG1
+	p.writeIO(0x32, 0x3F) // COLDATA: red 31
+	checkMasked(t, p, "prevent inside", [2]int{0, 9}, [2]int{21, 255})
+}
@@ -0,0 +1,72 @@
+package core
+
+// Words per tile and bitplanes in each color format
+var (
+	tileWords = [...]uint{COLOR_2BPP: 8, COLOR_4BPP: 16, COLOR_8BPP: 32}
+	colorBpp  = [...]int{COLOR_2BPP: 2, COLOR_4BPP: 4, COLOR_8BPP: 8}
+)
+
+// Decoded tile cache (bitplanes -> color index of each pixel)
+//
+// Tiles are keyed by format and VRAM address, and invalidated when their words are written.
+type tileCache struct {
+	disabled bool
+	pixels   [COLOR_8BPP + 1][]uint8 // [format][tile*64 + y*8 + x]
+	valid    [COLOR_8BPP + 1][]bool  // [format][tile]
+	row      [8]uint8                // decoded row when cache is disabled
+}
+
+func (c *tileCache) reset() {
+	for _, format := range [3]uint{COLOR_2BPP, COLOR_4BPP, COLOR_8BPP} {
+		n := (VRAM_SIZE / 2) / tileWords[format]
+		c.pixels[format] = make([]uint8, n*64)
+		c.valid[format] = make([]bool, n)
+	}
+}
+
+// Invalidate tiles which contain VRAM[idx] (unit is word)
+func (c *tileCache) invalidate(idx uint16) {
+	c.valid[COLOR_2BPP][idx>>3] = false
+	c.valid[COLOR_4BPP][idx>>4] = false
+	c.valid[COLOR_8BPP][idx>>5] = false
+}
+
+func (c *tileCache) flush() {
+	for _, valid := range c.valid {
+		for i := range valid {
+			valid[i] = false
+		}
+	}
+}
+
+// Color index of 8 pixels in y-th row of the tile at VRAM[start] (unit is word)
+func (v *vram) tileRow(format uint, start uint, y int) []uint8 {
+	c := &v.cache
+	if c.disabled || start%tileWords[format] != 0 {
+		decodeRow(c.row[:], v.buf[start:start+tileWords[format]], colorBpp[format], y)
+		return c.row[:]
+	}
+
+	n := start / tileWords[format]
+	pixels := c.pixels[format][n*64 : n*64+64]
+	if !c.valid[format][n] {
+		tile := v.buf[start : start+tileWords[format]]
+		for y := 0; y < 8; y++ {
+			decodeRow(pixels[y*8:y*8+8], tile, colorBpp[format], y)
+		}
+		c.valid[format][n] = true
+	}
+	return pixels[y*8 : y*8+8]
+}
+
+// Bitplanes 2n, 2n+1 of a row are in tile[y+8n]
+func decodeRow(dst []uint8, tile []uint16, bpp, y int) {
+	for i := 0; i < 8; i++ {
+		colorID := uint8(0)
+		for j := 0; j < bpp; j++ {
+			plane := uint8(tile[y+8*(j/2)] >> (8 * (j % 2)))
+			colorID += ((plane >> (7 - i)) & 0b1) << j
+		}
+		dst[i] = colorID
+	}
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"hash/crc32"
+	"testing"
+	"unsafe"
+
+	"github.com/pokemium/iro"
+)
+
+// Fill VRAM, CGRAM and OAM with pseudo random data and enable all layers in the BG mode
+func randomScene(s *sfc, seed uint32, mode uint8) {
+	p := s.ppu
+	p.reset()
+	p.writeIO(0x00, 0x0F) // INIDISP: full brightness
+
+	x := seed
+	rnd := func() uint32 { x ^= x << 13; x ^= x >> 17; x ^= x << 5; return x }
+	for i := range p.vram.buf {
+		p.vram.buf[i] = uint16(rnd())
+	}
+	for i := range p.pal.buf {
+		p.pal.buf[i] = iro.RGB555(rnd() & 0x7FFF)
+	}
+	for i := range p.oam.objs {
+		o := &p.oam.objs[i]
+		o.x, o.y, o.tile = uint16(rnd()%512), uint8(rnd()), uint8(rnd())
+		o.hflip, o.vflip, o.large, o.table2 = rnd()&1 == 1, rnd()&1 == 1, rnd()&1 == 1, rnd()&1 == 1
+		o.palID, o.prio = uint8(rnd()&7), uint8(rnd()&3)
+	}
+	p.writeIO(0x05, mode|0xF0) // BGMODE: 16x16 tiles
+	p.writeIO(0x07, 0x11)      // BG1SC..BG4SC
+	p.writeIO(0x08, 0x22)
+	p.writeIO(0x09, 0x33)
+	p.writeIO(0x0A, 0x43)
+	p.writeIO(0x0B, 0x21) // BG12NBA, BG34NBA
+	p.writeIO(0x0C, 0x65)
+	p.writeIO(0x01, 0x62) // OBSEL
+	p.writeIO(0x2C, 0x1F) // TM
+	p.writeIO(0x0D, 13)   // BG1HOFS
+	p.writeIO(0x0D, 0)
+	p.writeIO(0x0E, 7) // BG1VOFS
+	p.writeIO(0x0E, 0)
+	p.writeIO(0x30, byte(rnd()&1)) // CGWSEL: direct color
+}
+
+func drawFrame(s *sfc) {
+	for y := uint16(1); y <= VERTICAL; y++ {
+		s.ppu.r.drawScanline(y)
+	}
+}
+
+func frameHash(s *sfc) uint32 {
+	fb := s.ppu.r.frameBuffer()
+	return crc32.ChecksumIEEE(unsafe.Slice((*byte)(unsafe.Pointer(&fb[0])), len(fb)*2))
+}
+
+// Frames drawn with the tile cache must be the same as ones without it, also after VRAM is written
+func TestTileCacheFrameHash(t *testing.T) {
+	for mode := uint8(0); mode < 7; mode++ {
+		for seed := uint32(1); seed < 4; seed++ {
+			s := New().(*sfc)
+			randomScene(s, seed*7919, mode)
+
+			s.TileCache(false)
+			drawFrame(s)
+			want := frameHash(s)
+			s.TileCache(true)
+			drawFrame(s)
+			if got := frameHash(s); got != want {
+				t.Fatalf("mode %d seed %d: cached frame %08x, want %08x", mode, seed, got, want)
+			}
+
+			// VMAIN, VMADD, VMDATA
+			s.ppu.writeIO(0x15, 0x80)
+			s.ppu.writeIO(0x16, 0x00)
+			s.ppu.writeIO(0x17, 0x10)
+			for i := 0; i < 300; i++ {
+				s.ppu.writeIO(0x18, uint8(i*7))
+				s.ppu.writeIO(0x19, uint8(i*13))
+			}
+			drawFrame(s)
+			got := frameHash(s)
+			s.TileCache(false)
+			drawFrame(s)
+			if want := frameHash(s); got != want {
+				t.Fatalf("mode %d seed %d: cached frame after VRAM write %08x, want %08x", mode, seed, got, want)
+			}
+
+			blank := 0
+			for _, c := range s.ppu.r.frameBuffer() {
+				if c == 0 {
+					blank++
+				}
+			}
+			if blank > len(s.ppu.r.frameBuffer())/2 {
+				t.Fatalf("mode %d seed %d: frame is blank", mode, seed)
+			}
+		}
+	}
+}
+
+func benchmarkRender(b *testing.B, mode uint8) {
+	for _, cache := range []bool{true, false} {
+		name := "cache"
+		if !cache {
+			name = "nocache"
+		}
+		b.Run(name, func(b *testing.B) {
+			s := New().(*sfc)
+			randomScene(s, 12345, mode)
+			s.TileCache(cache)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				drawFrame(s)
+			}
+		})
+	}
+}
+
+func BenchmarkRenderMode1(b *testing.B) { benchmarkRender(b, 1) }
+func BenchmarkRenderMode3(b *testing.B) { benchmarkRender(b, 3) }
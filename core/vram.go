@@ -19,10 +19,15 @@ type vram struct {
 	incAmount  uint16    // VMAIN.0-1
 	fresh      bool
 	prefetched uint16
+	cache      tileCache
+	mapped     bool   // buf is mmapped and may be written from outside
+	writes     uint64 // write counter for debug viewers
 }
 
 func (v *vram) reset() {
 	v.buf = make([]uint16, VRAM_SIZE/2)
+	v.cache.reset()
+	v.writes++
 	v.idx = 0
 	v.incType = INC_LOW
 	v.rotate = rotates[0b11]
@@ -51,6 +56,8 @@ func (v *vram) read(hi bool) uint8 {
 func (v *vram) write(hi bool, val uint8) {
 	idx := ror(v.idx, v.rotate) & 0x7FFF
 	old := v.buf[idx]
+	v.cache.invalidate(idx)
+	v.writes++
 	if hi {
 		v.buf[idx] = (uint16(val) << 8) | (old & 0xFF)
 		if v.incType == INC_HIGH {
@@ -0,0 +1,58 @@
+package filter
+
+import (
+	"image"
+)
+
+// Scanlines darkens odd rows by Intensity (0..1), use it after 2x scaling
+type Scanlines struct {
+	Intensity float64
+	out       *image.RGBA
+}
+
+func (f *Scanlines) Apply(src *image.RGBA) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	f.out = output(f.out, w, h)
+	scale := uint32((1 - f.Intensity) * 256)
+	for y := 0; y < h; y++ {
+		s, d := row(src, y), row(f.out, y)
+		if y%2 == 0 {
+			copy(d, s)
+			continue
+		}
+		for i := range d {
+			d[i] = uint8((uint32(s[i]) * scale) >> 8)
+			if i%4 == 3 {
+				d[i] = s[i] // alpha
+			}
+		}
+	}
+	return f.out
+}
+
+// Mask emulates RGB aperture grille of CRT, each column keeps only one channel and the others are darkened by Intensity (0..1)
+type Mask struct {
+	Intensity float64
+	out       *image.RGBA
+}
+
+func (f *Mask) Apply(src *image.RGBA) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	f.out = output(f.out, w, h)
+	dim := uint32((1 - f.Intensity) * 256)
+	for y := 0; y < h; y++ {
+		s, d := row(src, y), row(f.out, y)
+		for x := 0; x < w; x++ {
+			for ch := 0; ch < 3; ch++ {
+				i := x*4 + ch
+				if x%3 == ch {
+					d[i] = s[i]
+				} else {
+					d[i] = uint8((uint32(s[i]) * dim) >> 8)
+				}
+			}
+			d[x*4+3] = s[x*4+3]
+		}
+	}
+	return f.out
+}
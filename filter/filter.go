@@ -0,0 +1,172 @@
+// Package filter is a video filter chain for the frame of emulator core
+//
+// All filters run on CPU (image.RGBA -> image.RGBA), so they can be used without GPU (e.g. headless screenshot).
+package filter
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+
+	"github.com/pokemium/gsnes/core"
+)
+
+// Filter converts an image into another one
+//
+// Returned image is owned by the filter and reused on next Apply, so copy it if you want to keep it.
+type Filter interface {
+	Apply(src *image.RGBA) *image.RGBA
+}
+
+// Chain applies filters in order
+type Chain []Filter
+
+func (c Chain) Apply(src *image.RGBA) *image.RGBA {
+	for _, f := range c {
+		src = f.Apply(src)
+	}
+	return src
+}
+
+// PixelAspect returns pixel aspect ratio of the output (8:7 if aspect correction is in the chain, 1 otherwise)
+func (c Chain) PixelAspect() float64 {
+	for _, f := range c {
+		if _, ok := f.(*Aspect); ok {
+			return PIXEL_ASPECT
+		}
+	}
+	return 1
+}
+
+// Presets are switched in order at runtime
+var Presets = []string{"none", "scale2x", "hq2x", "ntsc", "crt", "aspect,hq2x", "ntsc,crt"}
+
+// New builds a chain from comma separated filters (e.g. "hq2x,scanlines=0.4")
+//
+//	none               no filter
+//	nearest=WxH        nearest neighbor scaling into WxH
+//	integer=N          integer scaling (default: 2)
+//	aspect             8:7 pixel aspect correction
+//	scale2x            Scale2x (EPX)
+//	hq2x               HQ2x-style 2x scaler
+//	ntsc               NTSC composite video (output width is doubled)
+//	scanlines=I        darken odd rows by I (default: 0.5)
+//	mask=I             RGB aperture grille mask, other channels are darkened by I (default: 0.3)
+//	crt                integer=2,scanlines,mask
+func New(spec string) (Chain, error) {
+	c := Chain{}
+	for _, item := range strings.Split(spec, ",") {
+		name, arg := strings.TrimSpace(strings.ToLower(item)), ""
+		if i := strings.Index(name, "="); i >= 0 {
+			name, arg = name[:i], name[i+1:]
+		}
+
+		switch name {
+		case "", "none":
+		case "nearest":
+			var w, h int
+			if _, err := fmt.Sscanf(arg, "%dx%d", &w, &h); err != nil || w <= 0 || h <= 0 {
+				return nil, fmt.Errorf("invalid size for nearest: %s", arg)
+			}
+			c = append(c, &Nearest{W: w, H: h})
+		case "integer":
+			n, err := intArg(arg, 2)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid scale for integer: %s", arg)
+			}
+			c = append(c, &Integer{N: n})
+		case "aspect":
+			c = append(c, &Aspect{})
+		case "scale2x":
+			c = append(c, &Scale2x{})
+		case "hq2x":
+			c = append(c, &HQ2x{})
+		case "ntsc":
+			c = append(c, &NTSC{})
+		case "scanlines":
+			i, err := floatArg(arg, 0.5)
+			if err != nil {
+				return nil, fmt.Errorf("invalid intensity for scanlines: %s", arg)
+			}
+			c = append(c, &Scanlines{Intensity: i})
+		case "mask":
+			i, err := floatArg(arg, 0.3)
+			if err != nil {
+				return nil, fmt.Errorf("invalid intensity for mask: %s", arg)
+			}
+			c = append(c, &Mask{Intensity: i})
+		case "crt":
+			c = append(c, &Integer{N: 2}, &Scanlines{Intensity: 0.5}, &Mask{Intensity: 0.3})
+		default:
+			return nil, fmt.Errorf("unknown filter: %s", name)
+		}
+	}
+	return c, nil
+}
+
+func intArg(arg string, def int) (int, error) {
+	if arg == "" {
+		return def, nil
+	}
+	return strconv.Atoi(arg)
+}
+
+func floatArg(arg string, def float64) (float64, error) {
+	if arg == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(arg, 64)
+	if err != nil || f < 0 || f > 1 {
+		return 0, fmt.Errorf("out of range: %s", arg)
+	}
+	return f, nil
+}
+
+// Frame reads current frame of the core into dst (its buffer is reused if it's large enough)
+func Frame(c core.SuperFamicom, dst *image.RGBA) (*image.RGBA, error) {
+	w, h := c.Resolution()
+	if dst == nil || cap(dst.Pix) < w*h*4 {
+		dst = &image.RGBA{Pix: make([]uint8, w*h*4)}
+	}
+	dst.Pix, dst.Stride, dst.Rect = dst.Pix[:w*h*4], 4*w, image.Rect(0, 0, w, h)
+	return dst, c.ReadFrame(dst.Pix, core.FORMAT_RGBA8888)
+}
+
+// reuse dst if it has the same size
+func output(dst *image.RGBA, w, h int) *image.RGBA {
+	if dst == nil || dst.Rect.Dx() != w || dst.Rect.Dy() != h {
+		return image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+	return dst
+}
+
+// pixels of y-th row
+func row(img *image.RGBA, y int) []uint8 {
+	i := y * img.Stride
+	return img.Pix[i : i+img.Rect.Dx()*4]
+}
+
+// pixel as 0xAABBGGRR (coordinates are clamped into the image)
+func pixel(img *image.RGBA, x, y int) uint32 {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if x < 0 {
+		x = 0
+	} else if x >= w {
+		x = w - 1
+	}
+	if y < 0 {
+		y = 0
+	} else if y >= h {
+		y = h - 1
+	}
+	i := y*img.Stride + x*4
+	p := img.Pix[i : i+4 : i+4]
+	return uint32(p[0]) | uint32(p[1])<<8 | uint32(p[2])<<16 | uint32(p[3])<<24
+}
+
+func setPixel(img *image.RGBA, x, y int, c uint32) {
+	i := y*img.Stride + x*4
+	p := img.Pix[i : i+4 : i+4]
+	p[0], p[1], p[2], p[3] = uint8(c), uint8(c>>8), uint8(c>>16), uint8(c>>24)
+}
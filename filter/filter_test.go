@@ -0,0 +1,151 @@
+package filter
+
+import (
+	"image"
+	"testing"
+
+	"github.com/pokemium/gsnes/core"
+	"github.com/pokemium/gsnes/core/coretest"
+)
+
+const (
+	black = 0xFF000000
+	white = 0xFFFFFFFF
+	gray  = 0xFF808080
+)
+
+// w x h image filled with c
+func fill(w, h int, c uint32) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			setPixel(img, x, y, c)
+		}
+	}
+	return img
+}
+
+func TestNew(t *testing.T) {
+	src := fill(core.HORIZONTAL, core.VERTICAL, white)
+	for _, tt := range []struct {
+		spec string
+		w, h int
+	}{
+		{"none", 256, 224},
+		{"scale2x", 512, 448},
+		{"hq2x", 512, 448},
+		{"ntsc", 512, 224},
+		{"crt", 512, 448},
+		{"aspect", 293, 224},
+		{"ntsc,crt", 1024, 448},
+		{"nearest=320x240", 320, 240},
+		{"integer=3", 768, 672},
+		{" Scanlines=0.2 , mask ", 256, 224},
+	} {
+		c, err := New(tt.spec)
+		if err != nil {
+			t.Errorf("%s: %v", tt.spec, err)
+			continue
+		}
+		if r := c.Apply(src).Rect; r.Dx() != tt.w || r.Dy() != tt.h {
+			t.Errorf("%s: %dx%d, want %dx%d", tt.spec, r.Dx(), r.Dy(), tt.w, tt.h)
+		}
+	}
+
+	for _, spec := range []string{"bogus", "nearest=0x240", "integer=0", "scanlines=2", "hq2x,mask=x"} {
+		if _, err := New(spec); err == nil {
+			t.Errorf("%s: no error", spec)
+		}
+	}
+
+	for _, spec := range Presets {
+		if _, err := New(spec); err != nil {
+			t.Errorf("preset %s: %v", spec, err)
+		}
+	}
+}
+
+func TestPixelAspect(t *testing.T) {
+	if c, _ := New("hq2x"); c.PixelAspect() != 1 {
+		t.Errorf("hq2x: %f", c.PixelAspect())
+	}
+	if c, _ := New("aspect,hq2x"); c.PixelAspect() != PIXEL_ASPECT {
+		t.Errorf("aspect,hq2x: %f", c.PixelAspect())
+	}
+}
+
+func TestScale2x(t *testing.T) {
+	// the corner of black L shape is rounded
+	//
+	//	K K K
+	//	K W W
+	//	K W W
+	src := fill(3, 3, white)
+	for i := 0; i < 3; i++ {
+		setPixel(src, i, 0, black)
+		setPixel(src, 0, i, black)
+	}
+	out := (&Scale2x{}).Apply(src)
+	for _, tt := range []struct {
+		x, y int
+		want uint32
+	}{
+		{2, 2, black}, {3, 2, white}, {2, 3, white}, {3, 3, white}, {5, 5, white}, {0, 0, black},
+	} {
+		if got := pixel(out, tt.x, tt.y); got != tt.want {
+			t.Errorf("(%d, %d): %08X, want %08X", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestCRT(t *testing.T) {
+	c, _ := New("integer=2,scanlines=0.5")
+	out := c.Apply(fill(2, 2, white))
+	for y, want := range []uint32{white, 0xFF7F7F7F, white, 0xFF7F7F7F} {
+		if got := pixel(out, 0, y); got != want {
+			t.Errorf("row %d: %08X, want %08X", y, got, want)
+		}
+	}
+
+	// each column keeps one of R, G and B
+	out = (&Mask{Intensity: 1}).Apply(fill(3, 1, white))
+	for x, want := range []uint32{0xFF0000FF, 0xFF00FF00, 0xFFFF0000} {
+		if got := pixel(out, x, 0); got != want {
+			t.Errorf("column %d: %08X, want %08X", x, got, want)
+		}
+	}
+}
+
+func TestNTSC(t *testing.T) {
+	// flat gray has no artifacts except near the edges
+	out := (&NTSC{}).Apply(fill(64, 4, gray))
+	for _, x := range []int{32, 64, 96} {
+		got := pixel(out, x, 2)
+		for ch := 0; ch < 24; ch += 8 {
+			if d := int(got>>ch&0xFF) - 0x80; d < -4 || d > 4 {
+				t.Fatalf("(%d, 2): %08X", x, got)
+			}
+		}
+	}
+}
+
+func TestFrame(t *testing.T) {
+	c := core.New()
+	if err := c.LoadROM(coretest.LoROM(coretest.Counter)); err != nil {
+		t.Fatal(err)
+	}
+	c.RunFrame()
+
+	img, err := Frame(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := c.Resolution(); w != core.HORIZONTAL || img.Rect.Dx() != w || img.Rect.Dy() != h {
+		t.Fatalf("%v", img.Rect)
+	}
+
+	// buffer is reused
+	if again, _ := Frame(c, img); &again.Pix[0] != &img.Pix[0] {
+		t.Fatal("buffer isn't reused")
+	}
+}
@@ -0,0 +1,100 @@
+package filter
+
+import (
+	"image"
+	"math"
+)
+
+// NTSC simulates composite video of NTSC TV, colors bleed and artifacts appear around edges
+//
+// Each pixel is encoded into 6 samples of composite signal (4 samples per color subcarrier cycle, 1.5 cycles per pixel),
+// and decoded with low-pass filters as TV does. Output width is doubled (3 samples per pixel).
+type NTSC struct {
+	out             *image.RGBA
+	signal, luma    []float32 // samples of current line
+	sum, sumI, sumQ []float32 // prefix sums of signal and demodulated chroma
+}
+
+const NTSC_SAMPLES = 6 // samples per pixel
+
+// Subcarrier (cos, sin) of sample N%4, burst phase rotates by 120° every line
+var carrier = func() (t [3][4][2]float32) {
+	for line := range t {
+		for n := range t[line] {
+			phase := math.Pi/2*float64(n) + 2*math.Pi/3*float64(line)
+			t[line][n] = [2]float32{float32(math.Cos(phase)), float32(math.Sin(phase))}
+		}
+	}
+	return t
+}()
+
+func (f *NTSC) Apply(src *image.RGBA) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	f.out = output(f.out, w*2, h)
+
+	n := w * NTSC_SAMPLES
+	if len(f.signal) != n {
+		f.signal, f.luma = make([]float32, n), make([]float32, n)
+		f.sum, f.sumI, f.sumQ = make([]float32, n+1), make([]float32, n+1), make([]float32, n+1)
+	}
+
+	for y := 0; y < h; y++ {
+		sc := &carrier[y%3]
+
+		// encode: RGB -> YIQ -> composite
+		line := row(src, y)
+		for x := 0; x < w; x++ {
+			r, g, b := float32(line[x*4])/255, float32(line[x*4+1])/255, float32(line[x*4+2])/255
+			yy := 0.299*r + 0.587*g + 0.114*b
+			ii := 0.596*r - 0.274*g - 0.322*b
+			qq := 0.211*r - 0.523*g + 0.312*b
+			for s := 0; s < NTSC_SAMPLES; s++ {
+				k := x*NTSC_SAMPLES + s
+				f.signal[k] = yy + ii*sc[k%4][0] + qq*sc[k%4][1]
+			}
+		}
+
+		// decode: averaging a subcarrier cycle cancels chroma, and chroma is demodulated and low-passed
+		for k, s := range f.signal {
+			f.sum[k+1] = f.sum[k] + s
+		}
+		for k, s := range f.signal {
+			f.luma[k] = average(f.sum, k, 4)
+			c := s - f.luma[k]
+			f.sumI[k+1] = f.sumI[k] + 2*c*sc[k%4][0]
+			f.sumQ[k+1] = f.sumQ[k] + 2*c*sc[k%4][1]
+		}
+
+		for x := 0; x < w*2; x++ {
+			k := x*3 + 1
+			yy, ii, qq := f.luma[k], average(f.sumI, k, 8), average(f.sumQ, k, 8)
+			r := yy + 0.956*ii + 0.621*qq
+			g := yy - 0.272*ii - 0.647*qq
+			b := yy - 1.106*ii + 1.703*qq
+			setPixel(f.out, x, y, uint32(clamp8(r))|uint32(clamp8(g))<<8|uint32(clamp8(b))<<16|0xFF<<24)
+		}
+	}
+	return f.out
+}
+
+// Moving average over samples[k-size/2 : k+size/2] from prefix sums
+func average(sum []float32, k, size int) float32 {
+	from, to := k-size/2, k+size/2
+	if from < 0 {
+		from = 0
+	}
+	if to >= len(sum) {
+		to = len(sum) - 1
+	}
+	return (sum[to] - sum[from]) / float32(to-from)
+}
+
+func clamp8(c float32) uint8 {
+	switch {
+	case c <= 0:
+		return 0
+	case c >= 1:
+		return 0xFF
+	}
+	return uint8(c*255 + 0.5)
+}
@@ -0,0 +1,85 @@
+package filter
+
+import (
+	"image"
+)
+
+// PIXEL_ASPECT is pixel aspect ratio of SNES on NTSC TV
+const PIXEL_ASPECT = 8.0 / 7.0
+
+// Nearest scales an image into WxH with nearest neighbor
+type Nearest struct {
+	W, H int
+	out  *image.RGBA
+}
+
+func (f *Nearest) Apply(src *image.RGBA) *image.RGBA {
+	f.out = output(f.out, f.W, f.H)
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	for y := 0; y < f.H; y++ {
+		sy := y * sh / f.H
+		for x := 0; x < f.W; x++ {
+			setPixel(f.out, x, y, pixel(src, x*sw/f.W, sy))
+		}
+	}
+	return f.out
+}
+
+// Integer scales an image by N with nearest neighbor
+type Integer struct {
+	N   int
+	out *image.RGBA
+}
+
+func (f *Integer) Apply(src *image.RGBA) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	f.out = output(f.out, w*f.N, h*f.N)
+	for y := 0; y < h; y++ {
+		// write a row once, and copy it into the other N-1 rows
+		row := f.out.Pix[y*f.N*f.out.Stride : (y*f.N+1)*f.out.Stride]
+		for x := 0; x < w; x++ {
+			c := pixel(src, x, y)
+			for i := 0; i < f.N; i++ {
+				setPixel(f.out, x*f.N+i, y*f.N, c)
+			}
+		}
+		for i := 1; i < f.N; i++ {
+			copy(f.out.Pix[(y*f.N+i)*f.out.Stride:], row)
+		}
+	}
+	return f.out
+}
+
+// Aspect stretches an image horizontally by 8:7 with linear interpolation
+type Aspect struct {
+	out *image.RGBA
+}
+
+func (f *Aspect) Apply(src *image.RGBA) *image.RGBA {
+	sw, h := src.Rect.Dx(), src.Rect.Dy()
+	w := int(float64(sw)*PIXEL_ASPECT + 0.5)
+	f.out = output(f.out, w, h)
+	for x := 0; x < w; x++ {
+		// center of the output pixel on source image
+		sx := (float64(x)+0.5)*float64(sw)/float64(w) - 0.5
+		x0 := int(sx)
+		if sx < 0 {
+			x0 = -1
+		}
+		t := uint32((sx - float64(x0)) * 256)
+		for y := 0; y < h; y++ {
+			setPixel(f.out, x, y, lerp(pixel(src, x0, y), pixel(src, x0+1, y), t))
+		}
+	}
+	return f.out
+}
+
+// a*(256-t)/256 + b*t/256 for each channel
+func lerp(a, b, t uint32) uint32 {
+	result := uint32(0)
+	for shift := 0; shift < 32; shift += 8 {
+		ca, cb := (a>>shift)&0xFF, (b>>shift)&0xFF
+		result |= ((ca*(256-t) + cb*t) >> 8) << shift
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"image"
+)
+
+// Scale2x scales an image by 2 with EPX algorithm (edges are kept sharp)
+type Scale2x struct {
+	out *image.RGBA
+}
+
+func (f *Scale2x) Apply(src *image.RGBA) *image.RGBA {
+	f.out = scale2(f.out, src, func(e, corner, p, q, op, oq uint32) uint32 {
+		if p == q && p != oq && q != op {
+			return p
+		}
+		return e
+	})
+	return f.out
+}
+
+// HQ2x is simplified HQ2x, it compares colors in YUV and blends the pixels on the edges instead of copying
+type HQ2x struct {
+	out *image.RGBA
+}
+
+func (f *HQ2x) Apply(src *image.RGBA) *image.RGBA {
+	f.out = scale2(f.out, src, func(e, corner, p, q, op, oq uint32) uint32 {
+		switch {
+		case !diff(p, q) && diff(e, p):
+			return blend(e, 2, p, 1, q, 1) // edge crosses the corner
+		case diff(e, corner) && (!diff(e, p) || !diff(e, q)):
+			return blend(e, 3, corner, 1, e, 0)
+		}
+		return e
+	})
+	return f.out
+}
+
+// Each pixel E is split into 4 pixels, fn decides each of them from neighbors
+//
+//	A B C
+//	D E F
+//	G H I
+//
+// e.g. top-left of E: corner=A, p=B, q=D, op=H(opposite of p), oq=F(opposite of q)
+func scale2(dst, src *image.RGBA, fn func(e, corner, p, q, op, oq uint32) uint32) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst = output(dst, w*2, h*2)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			a, b, c := pixel(src, x-1, y-1), pixel(src, x, y-1), pixel(src, x+1, y-1)
+			d, e, f := pixel(src, x-1, y), pixel(src, x, y), pixel(src, x+1, y)
+			g, hh, i := pixel(src, x-1, y+1), pixel(src, x, y+1), pixel(src, x+1, y+1)
+
+			setPixel(dst, x*2, y*2, fn(e, a, b, d, hh, f))
+			setPixel(dst, x*2+1, y*2, fn(e, c, b, f, hh, d))
+			setPixel(dst, x*2, y*2+1, fn(e, g, hh, d, b, f))
+			setPixel(dst, x*2+1, y*2+1, fn(e, i, hh, f, b, d))
+		}
+	}
+	return dst
+}
+
+// Two colors are different if YUV difference exceeds HQ2x thresholds
+func diff(a, b uint32) bool {
+	if a == b {
+		return false
+	}
+	y1, u1, v1 := yuv(a)
+	y2, u2, v2 := yuv(b)
+	return abs(y1-y2) > 0x30 || abs(u1-u2) > 7 || abs(v1-v2) > 6
+}
+
+func yuv(c uint32) (y, u, v int) {
+	r, g, b := int(c&0xFF), int((c>>8)&0xFF), int((c>>16)&0xFF)
+	return (r + g + b) >> 2, 128 + ((r - b) >> 2), 128 + ((-r + 2*g - b) >> 3)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Weighted average of 3 colors for each channel
+func blend(a, wa, b, wb, c, wc uint32) uint32 {
+	sum := wa + wb + wc
+	result := uint32(0)
+	for shift := 0; shift < 32; shift += 8 {
+		ca, cb, cc := (a>>shift)&0xFF, (b>>shift)&0xFF, (c>>shift)&0xFF
+		result |= ((ca*wa + cb*wb + cc*wc) / sum) << shift
+	}
+	return result
+}
@@ -0,0 +1,381 @@
+package gdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pokemium/gsnes/core"
+)
+
+// Register layout of g/G packets (little endian): a, x, y, s, d, db, pc (PB:PC), p, e
+var regSize = []int{2, 2, 2, 2, 2, 1, 4, 1, 1}
+
+const targetXML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <feature name="org.gsnes.w65816">
+    <reg name="a" bitsize="16" regnum="0"/>
+    <reg name="x" bitsize="16"/>
+    <reg name="y" bitsize="16"/>
+    <reg name="s" bitsize="16" type="data_ptr"/>
+    <reg name="d" bitsize="16"/>
+    <reg name="db" bitsize="8"/>
+    <reg name="pc" bitsize="32" type="code_ptr"/>
+    <reg name="p" bitsize="8"/>
+    <reg name="e" bitsize="8"/>
+  </feature>
+</target>
+`
+
+// Handle a packet, ok is false if it has no reply now (continue, kill)
+func (s *Server) command(data string) (reply string, ok bool) {
+	if data == "" {
+		return "", true
+	}
+
+	switch data[0] {
+	case '?':
+		return s.stopReply(), true
+
+	case 'q', 'Q':
+		return s.query(data), true
+
+	case 'H', 'T':
+		return "OK", true
+
+	case 'g':
+		hex := ""
+		for i, v := range regValues(s.core.Registers()) {
+			hex += encode(v, regSize[i])
+		}
+		return hex, true
+
+	case 'G':
+		r := s.core.Registers()
+		hex := data[1:]
+		for i, size := range regSize {
+			if len(hex) < size*2 {
+				return "E01", true
+			}
+			v, err := decode(hex[:size*2])
+			if err != nil {
+				return "E01", true
+			}
+			setReg(&r, i, v)
+			hex = hex[size*2:]
+		}
+		return result(s.core.SetRegisters(r)), true
+
+	case 'p':
+		n, err := strconv.ParseUint(data[1:], 16, 8)
+		if err != nil || int(n) >= len(regSize) {
+			return "E01", true
+		}
+		return encode(regValues(s.core.Registers())[n], regSize[n]), true
+
+	case 'P':
+		p := strings.SplitN(data[1:], "=", 2)
+		if len(p) != 2 {
+			return "E01", true
+		}
+		n, err := strconv.ParseUint(p[0], 16, 8)
+		if err != nil || int(n) >= len(regSize) {
+			return "E01", true
+		}
+		v, err := decode(p[1])
+		if err != nil {
+			return "E01", true
+		}
+		r := s.core.Registers()
+		setReg(&r, int(n), v)
+		return result(s.core.SetRegisters(r)), true
+
+	case 'm':
+		addr, size, err := addrLen(data[1:])
+		if err != nil {
+			return "E01", true
+		}
+		return fmt.Sprintf("%x", s.core.ReadMemory(addr, size)), true
+
+	case 'M':
+		p := strings.SplitN(data[1:], ":", 2)
+		if len(p) != 2 {
+			return "E01", true
+		}
+		addr, size, err := addrLen(p[0])
+		if err != nil || len(p[1]) != size*2 {
+			return "E01", true
+		}
+		buf := make([]uint8, size)
+		for i := range buf {
+			v, err := strconv.ParseUint(p[1][i*2:i*2+2], 16, 8)
+			if err != nil {
+				return "E01", true
+			}
+			buf[i] = uint8(v)
+		}
+		return result(s.core.WriteMemory(addr, buf)), true
+
+	case 'c', 's':
+		if len(data) > 1 {
+			if err := s.jump(data[1:]); err != nil {
+				return "E01", true
+			}
+		}
+		return s.resume(data[0] == 's')
+
+	case 'v':
+		switch {
+		case data == "vCont?":
+			return "vCont;c;C;s;S", true
+		case strings.HasPrefix(data, "vCont;") && len(data) > len("vCont;"):
+			action := data[len("vCont;")]
+			return s.resume(action == 's' || action == 'S')
+		}
+		return "", true
+
+	case 'Z', 'z':
+		return s.breakpoint(data), true
+
+	case 'D':
+		s.send("OK")
+		s.detach()
+		return "", false
+
+	case 'k':
+		s.detach()
+		return "", false
+	}
+
+	return "", true // unsupported
+}
+
+func (s *Server) query(data string) string {
+	switch {
+	case strings.HasPrefix(data, "qSupported"):
+		return "PacketSize=1000;qXfer:features:read+;QStartNoAckMode+;swbreak+;hwbreak+"
+
+	case data == "QStartNoAckMode":
+		s.mu.Lock()
+		s.noAck = true
+		s.mu.Unlock()
+		return "OK"
+
+	case strings.HasPrefix(data, "qXfer:features:read:"):
+		// qXfer:features:read:target.xml:offset,length
+		p := strings.Split(strings.TrimPrefix(data, "qXfer:features:read:"), ":")
+		if len(p) != 2 || p[0] != "target.xml" {
+			return "E00"
+		}
+		ofs, size, err := addrLen(p[1])
+		if err != nil {
+			return "E01"
+		}
+		if int(ofs) >= len(targetXML) {
+			return "l"
+		}
+		end := int(ofs) + size
+		if end >= len(targetXML) {
+			return "l" + targetXML[ofs:]
+		}
+		return "m" + targetXML[ofs:end]
+
+	case data == "qAttached":
+		return "1"
+	case data == "qC":
+		return "QC1"
+	case data == "qfThreadInfo":
+		return "m1"
+	case data == "qsThreadInfo":
+		return "l"
+	case strings.HasPrefix(data, "qSymbol"):
+		return "OK"
+	}
+	return ""
+}
+
+// Step an instruction or continue until the core pauses
+func (s *Server) resume(step bool) (string, bool) {
+	s.interrupt = false
+	if step {
+		s.core.Pause(true) // clear the last break reason
+		s.core.RunInst()
+		return s.stopReply(), true
+	}
+	s.running = true
+	s.core.Pause(false)
+	return "", false
+}
+
+// Set PC by "c addr" and "s addr"
+func (s *Server) jump(hex string) error {
+	addr, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return err
+	}
+	r := s.core.Registers()
+	r.PC = uint32(addr) & 0xFF_FFFF
+	return s.core.SetRegisters(r)
+}
+
+// Z/z type,addr,kind (0: software, 1: hardware, 2: write, 3: read, 4: access watchpoint)
+func (s *Server) breakpoint(data string) string {
+	key := data[1:]
+	p := strings.SplitN(key, ",", 2)
+	if len(p) != 2 || len(p[0]) != 1 {
+		return "E01"
+	}
+	typ := p[0][0]
+	addr, size, err := addrLen(strings.SplitN(p[1], ";", 2)[0])
+	if err != nil {
+		return "E01"
+	}
+
+	if data[0] == 'z' {
+		for _, id := range s.bkpts[key] {
+			s.core.RemoveBreakpoint(id)
+			delete(s.kinds, id)
+		}
+		delete(s.bkpts, key)
+		return "OK"
+	}
+
+	if _, ok := s.bkpts[key]; ok {
+		return "OK"
+	}
+
+	r := core.AddrRange{Start: addr, End: addr}
+	if typ >= '2' && size > 1 {
+		r.End = addr + uint32(size) - 1
+	}
+
+	kinds := []string{}
+	switch typ {
+	case '0', '1':
+		kinds = append(kinds, core.BREAK_EXEC)
+	case '2':
+		kinds = append(kinds, core.BREAK_WRITE)
+	case '3':
+		kinds = append(kinds, core.BREAK_READ)
+	case '4':
+		kinds = append(kinds, core.BREAK_READ, core.BREAK_WRITE)
+	default:
+		return "" // unsupported
+	}
+
+	for _, kind := range kinds {
+		id, err := s.core.AddBreakpoint(kind, r, "")
+		if err != nil {
+			return "E01"
+		}
+		s.bkpts[key] = append(s.bkpts[key], id)
+		s.kinds[id] = typ
+	}
+	return "OK"
+}
+
+// Stop reply packet (T05: SIGTRAP, T02: SIGINT)
+func (s *Server) stopReply() string {
+	if s.interrupt {
+		s.interrupt = false
+		return "T02thread:1;"
+	}
+
+	b, ok := s.core.BreakReason()
+	if !ok {
+		return "T05thread:1;"
+	}
+	switch s.kinds[b.ID] {
+	case '0':
+		return "T05swbreak:;thread:1;"
+	case '1':
+		return "T05hwbreak:;thread:1;"
+	case '2':
+		return fmt.Sprintf("T05watch:%x;thread:1;", b.Addr)
+	case '3':
+		return fmt.Sprintf("T05rwatch:%x;thread:1;", b.Addr)
+	case '4':
+		return fmt.Sprintf("T05awatch:%x;thread:1;", b.Addr)
+	}
+	return "T05thread:1;" // breakpoint not by the client
+}
+
+func regValues(r core.Registers) []uint32 {
+	e := uint32(0)
+	if r.E {
+		e = 1
+	}
+	return []uint32{
+		uint32(r.A), uint32(r.X), uint32(r.Y), uint32(r.S), uint32(r.D),
+		uint32(r.DB), r.PC, uint32(r.P), e,
+	}
+}
+
+func setReg(r *core.Registers, n int, v uint32) {
+	switch n {
+	case 0:
+		r.A = uint16(v)
+	case 1:
+		r.X = uint16(v)
+	case 2:
+		r.Y = uint16(v)
+	case 3:
+		r.S = uint16(v)
+	case 4:
+		r.D = uint16(v)
+	case 5:
+		r.DB = uint8(v)
+	case 6:
+		r.PC = v & 0xFF_FFFF
+	case 7:
+		r.P = uint8(v)
+	case 8:
+		r.E = v != 0
+	}
+}
+
+// Little endian hex
+func encode(v uint32, size int) string {
+	hex := ""
+	for i := 0; i < size; i++ {
+		hex += fmt.Sprintf("%02x", uint8(v>>(8*i)))
+	}
+	return hex
+}
+
+func decode(hex string) (uint32, error) {
+	v := uint32(0)
+	for i := 0; i+2 <= len(hex) && i < 8; i += 2 {
+		b, err := strconv.ParseUint(hex[i:i+2], 16, 8)
+		if err != nil {
+			return 0, err
+		}
+		v |= uint32(b) << (4 * i)
+	}
+	return v, nil
+}
+
+// "addr,length" in hex
+func addrLen(s string) (uint32, int, error) {
+	p := strings.Split(s, ",")
+	if len(p) != 2 {
+		return 0, 0, fmt.Errorf("invalid address and length: %s", s)
+	}
+	addr, err := strconv.ParseUint(p[0], 16, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, err := strconv.ParseUint(p[1], 16, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint32(addr), int(size), nil
+}
+
+func result(err error) string {
+	if err != nil {
+		return "E01"
+	}
+	return "OK"
+}
@@ -0,0 +1,99 @@
+package gdb
+
+import (
+	"testing"
+
+	"github.com/pokemium/gsnes/core"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	for _, tt := range []struct {
+		v    uint32
+		size int
+		hex  string
+	}{
+		{0x34, 1, "34"},
+		{0x1234, 2, "3412"},
+		{0x008000, 4, "00800000"},
+		{0x7E1234, 4, "34127e00"},
+		{0x12345678, 4, "78563412"},
+	} {
+		if got := encode(tt.v, tt.size); got != tt.hex {
+			t.Errorf("encode(%X, %d): got %s, want %s", tt.v, tt.size, got, tt.hex)
+		}
+		if got, err := decode(tt.hex); err != nil || got != tt.v {
+			t.Errorf("decode(%s): got %X %v, want %X", tt.hex, got, err, tt.v)
+		}
+	}
+
+	if _, err := decode("zz"); err == nil {
+		t.Error("decode(zz): no error")
+	}
+}
+
+func TestRegisterLayout(t *testing.T) {
+	r := core.Registers{A: 0x1234, X: 0x5678, Y: 0x9ABC, S: 0x01FF, D: 0x0100, DB: 0x7E, PC: 0x018000, P: 0x30, E: true}
+	v := regValues(r)
+	if len(v) != len(regSize) {
+		t.Fatalf("%d registers, %d sizes", len(v), len(regSize))
+	}
+
+	got := core.Registers{}
+	for i := range v {
+		setReg(&got, i, v[i])
+	}
+	if got != r {
+		t.Errorf("got %+v, want %+v", got, r)
+	}
+}
+
+func TestBreakpointPacket(t *testing.T) {
+	c := core.New()
+	s := &Server{core: c, bkpts: map[string][]int{}, kinds: map[int]byte{}}
+
+	for _, tt := range []struct {
+		packet string
+		kinds  []string
+		r      core.AddrRange
+	}{
+		{"Z0,8006,1", []string{core.BREAK_EXEC}, core.AddrRange{Start: 0x8006, End: 0x8006}},
+		{"Z1,7e8000,2", []string{core.BREAK_EXEC}, core.AddrRange{Start: 0x7E8000, End: 0x7E8000}},
+		{"Z2,2118,2", []string{core.BREAK_WRITE}, core.AddrRange{Start: 0x2118, End: 0x2119}},
+		{"Z3,7e0010,1", []string{core.BREAK_READ}, core.AddrRange{Start: 0x7E0010, End: 0x7E0010}},
+		{"Z4,10,4", []string{core.BREAK_READ, core.BREAK_WRITE}, core.AddrRange{Start: 0x10, End: 0x13}},
+	} {
+		if reply := s.breakpoint(tt.packet); reply != "OK" {
+			t.Fatalf("%s: %s", tt.packet, reply)
+		}
+
+		bps := c.Breakpoints()
+		if len(bps) != len(tt.kinds) {
+			t.Fatalf("%s: %v", tt.packet, bps)
+		}
+		for i, bp := range bps {
+			if bp.Kind != tt.kinds[i] || bp.Range != tt.r || bp.Condition != "" || s.kinds[bp.ID] != tt.packet[1] {
+				t.Errorf("%s: %+v", tt.packet, bp)
+			}
+		}
+
+		if reply := s.breakpoint("z" + tt.packet[1:]); reply != "OK" || len(c.Breakpoints()) != 0 {
+			t.Fatalf("z%s: %s %v", tt.packet[1:], reply, c.Breakpoints())
+		}
+	}
+
+	// same breakpoint is added once
+	s.breakpoint("Z0,8000,1")
+	s.breakpoint("Z0,8000,1")
+	if len(c.Breakpoints()) != 1 {
+		t.Errorf("%v", c.Breakpoints())
+	}
+
+	for _, p := range []string{"Z0", "Z0,8000", "Z,8000,1", "Z0,zz,1"} {
+		if reply := s.breakpoint(p); reply != "E01" {
+			t.Errorf("%s: got %q, want E01", p, reply)
+		}
+	}
+	if reply := s.breakpoint("Z5,8000,1"); reply != "" {
+		t.Errorf("Z5: got %q, want unsupported", reply)
+	}
+}
@@ -0,0 +1,214 @@
+// Package gdb is a GDB remote serial protocol stub for the emulator core
+//
+// Network I/O runs on its own goroutines and packets are queued,
+// they are handled on the emulation goroutine by Update so that the core is never accessed concurrently.
+package gdb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/pokemium/gsnes/core"
+)
+
+const (
+	EVENT_CONNECT   = iota // client is connected
+	EVENT_PACKET           // packet is received
+	EVENT_INTERRUPT        // Ctrl-C (0x03) is received
+	EVENT_CLOSE            // client is disconnected
+)
+
+type event struct {
+	kind int
+	conn net.Conn
+	data string
+}
+
+// Server accepts one GDB client at a time
+type Server struct {
+	core   core.SuperFamicom
+	l      net.Listener
+	events chan event
+
+	conn      net.Conn
+	mu        sync.Mutex // guards noAck and writes into conn
+	noAck     bool
+	running   bool // continued by client, stop reply is sent when the core pauses
+	interrupt bool // paused by Ctrl-C
+	bkpts     map[string][]int
+	kinds     map[int]byte // breakpoint ID -> Z packet type ('0'..'4')
+}
+
+// Listen starts the stub on the TCP address (e.g. "localhost:2345")
+func Listen(addr string, c core.SuperFamicom) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		core:   c,
+		l:      l,
+		events: make(chan event, 64),
+		bkpts:  map[string][]int{},
+		kinds:  map[int]byte{},
+	}
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns the address the stub is listening on
+func (s *Server) Addr() net.Addr {
+	return s.l.Addr()
+}
+
+// Close stops the stub and disconnects the client
+func (s *Server) Close() error {
+	err := s.l.Close()
+	if s.conn != nil {
+		s.detach()
+	}
+	return err
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.events <- event{kind: EVENT_CONNECT, conn: conn}
+		go s.read(conn)
+	}
+}
+
+// Read packets from the client and ack them
+func (s *Server) read(conn net.Conn) {
+	defer func() {
+		s.events <- event{kind: EVENT_CLOSE, conn: conn}
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+
+		switch b {
+		case 0x03:
+			s.events <- event{kind: EVENT_INTERRUPT, conn: conn}
+
+		case '$':
+			data, err := r.ReadString('#')
+			if err != nil {
+				return
+			}
+			data = data[:len(data)-1]
+
+			sum := make([]byte, 2)
+			if _, err := io.ReadFull(r, sum); err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			if !s.noAck {
+				if fmt.Sprintf("%02x", checksum(data)) != string(sum) {
+					conn.Write([]byte("-"))
+					s.mu.Unlock()
+					continue
+				}
+				conn.Write([]byte("+"))
+			}
+			s.mu.Unlock()
+			s.events <- event{kind: EVENT_PACKET, conn: conn, data: data}
+		}
+	}
+}
+
+func checksum(data string) uint8 {
+	sum := uint8(0)
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+// Send a packet to the client
+func (s *Server) send(data string) {
+	if s.conn == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.conn, "$%s#%02x", data, checksum(data))
+}
+
+// Update handles received packets and reports stops, call it on the emulation goroutine every frame
+func (s *Server) Update() {
+	for {
+		select {
+		case ev := <-s.events:
+			s.handle(ev)
+		default:
+			if s.running && s.core.Paused() {
+				s.running = false
+				s.send(s.stopReply())
+			}
+			return
+		}
+	}
+}
+
+func (s *Server) handle(ev event) {
+	switch ev.kind {
+	case EVENT_CONNECT:
+		if s.conn != nil {
+			ev.conn.Close() // only one client
+			return
+		}
+		s.mu.Lock()
+		s.noAck = false
+		s.mu.Unlock()
+		s.conn, s.running, s.interrupt = ev.conn, false, false
+		s.core.Pause(true)
+		return
+	}
+
+	if ev.conn != s.conn {
+		return
+	}
+
+	switch ev.kind {
+	case EVENT_PACKET:
+		if reply, ok := s.command(ev.data); ok {
+			s.send(reply)
+		}
+
+	case EVENT_INTERRUPT:
+		if s.running {
+			s.interrupt = true
+			s.core.Pause(true)
+		}
+
+	case EVENT_CLOSE:
+		s.detach()
+	}
+}
+
+// Remove breakpoints by client, disconnect and resume the core
+func (s *Server) detach() {
+	for _, ids := range s.bkpts {
+		for _, id := range ids {
+			s.core.RemoveBreakpoint(id)
+		}
+	}
+	s.bkpts, s.kinds = map[string][]int{}, map[int]byte{}
+
+	s.conn.Close()
+	s.conn, s.running = nil, false
+	s.core.Pause(false)
+}
@@ -0,0 +1,296 @@
+package gdb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pokemium/gsnes/core"
+	"github.com/pokemium/gsnes/core/coretest"
+)
+
+func newTestCore(t *testing.T) core.SuperFamicom {
+	t.Helper()
+	c := core.New()
+	if err := c.LoadROM(coretest.LoROM(coretest.Counter)); err != nil {
+		t.Fatal(err)
+	}
+	c.Pause(true) // registers are at reset when the client connects
+	return c
+}
+
+// GDB client
+type client struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	noAck bool
+}
+
+func (c *client) send(data string) error {
+	if _, err := fmt.Fprintf(c.conn, "$%s#%02x", data, checksum(data)); err != nil {
+		return err
+	}
+	if c.noAck {
+		return nil
+	}
+	b, err := c.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if b != '+' {
+		return fmt.Errorf("%s: ack is %q", data, b)
+	}
+	return nil
+}
+
+func (c *client) recv() (string, error) {
+	b, err := c.r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if b != '$' {
+		return "", fmt.Errorf("packet starts with %q", b)
+	}
+	data, err := c.r.ReadString('#')
+	if err != nil {
+		return "", err
+	}
+	data = data[:len(data)-1]
+	sum := make([]byte, 2)
+	if _, err := io.ReadFull(c.r, sum); err != nil {
+		return "", err
+	}
+	if string(sum) != fmt.Sprintf("%02x", checksum(data)) {
+		return "", fmt.Errorf("%s: invalid checksum %s", data, sum)
+	}
+	if !c.noAck {
+		c.conn.Write([]byte("+"))
+	}
+	return data, nil
+}
+
+func (c *client) command(data string) (string, error) {
+	if err := c.send(data); err != nil {
+		return "", err
+	}
+	return c.recv()
+}
+
+// Run the emulation loop until f returns
+func runClient(t *testing.T, c core.SuperFamicom, srv *Server, f func(cl *client) error) {
+	t.Helper()
+	done := make(chan error)
+	go func() {
+		conn, err := net.Dial("tcp", srv.Addr().String())
+		if err != nil {
+			done <- err
+			return
+		}
+		defer conn.Close()
+		done <- f(&client{conn: conn, r: bufio.NewReader(conn)})
+	}()
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(20 * time.Millisecond) // wait for EVENT_CLOSE
+			srv.Update()
+			return
+		case <-timeout:
+			t.Fatal("timeout")
+		default:
+		}
+		srv.Update()
+		if c.Paused() {
+			time.Sleep(time.Millisecond)
+		} else {
+			c.RunFrame()
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	c := newTestCore(t)
+	srv, err := Listen("localhost:0", c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	runClient(t, c, srv, func(cl *client) error {
+		expect := func(cmd, want string) error {
+			got, err := cl.command(cmd)
+			if err != nil {
+				return err
+			}
+			if got != want {
+				return fmt.Errorf("%s: got %q, want %q", cmd, got, want)
+			}
+			return nil
+		}
+
+		// packet with invalid checksum is nacked
+		fmt.Fprint(cl.conn, "$g#00")
+		if b, err := cl.r.ReadByte(); err != nil || b != '-' {
+			return fmt.Errorf("invalid checksum: got %q %v, want '-'", b, err)
+		}
+
+		reply, err := cl.command("qSupported:multiprocess+;swbreak+")
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(reply, "QStartNoAckMode+") || !strings.Contains(reply, "swbreak+") {
+			return fmt.Errorf("qSupported: %q", reply)
+		}
+
+		for _, tt := range []struct{ cmd, want string }{
+			{"?", "T05thread:1;"},
+
+			// a, x, y, s, d, db, pc, p, e
+			{"g", "0000" + "0000" + "0000" + "ff01" + "0000" + "00" + "00800000" + "34" + "01"},
+			{"p3", "ff01"},
+			{"p6", "00800000"},
+			{"p9", "E01"},
+			{"P0=3412", "OK"},
+			{"p0", "3412"},
+			{"G" + "7856" + "0802" + "0301" + "f001" + "0001" + "7e" + "00800000" + "04" + "00", "OK"},
+			{"g", "7856" + "0802" + "0301" + "f001" + "0001" + "7e" + "00800000" + "04" + "00"},
+			{"G0000", "E01"},
+			{"G" + "0000" + "0000" + "0000" + "ff01" + "0000" + "00" + "00800000" + "34" + "01", "OK"},
+			{"p1", "0000"},
+
+			{"m8000,f", "78a200e88610a91f8d1821a51080f4"},
+			{"M7e0100,2,:abcd", "E01"},
+			{"M7e0100,2:abc", "E01"},
+			{"M7e0100,2:abcd", "OK"},
+			{"m7e0100,2", "abcd"},
+			{"M8000,1:00", "OK"}, // ROM is read only
+			{"m8000,1", "78"},
+
+			{"Z0,8006,1", "OK"},
+		} {
+			if err := expect(tt.cmd, tt.want); err != nil {
+				return err
+			}
+		}
+
+		// continue until the breakpoint
+		if err := cl.send("c"); err != nil {
+			return err
+		}
+		for _, tt := range []struct{ cmd, want string }{
+			{"", "T05swbreak:;thread:1;"},
+			{"p6", "06800000"},
+			{"s", "T05thread:1;"},
+			{"p6", "08800000"},
+			{"z0,8006,1", "OK"},
+			{"Z2,2118,2", "OK"},
+			{"c", "T05watch:2118;thread:1;"},
+			{"p6", "0b800000"}, // STA $2118 is finished
+			{"z2,2118,2", "OK"},
+			{"Z3,10,1", "OK"},
+			{"c", "T05rwatch:10;thread:1;"},
+			{"z3,10,1", "OK"},
+			{"Z4,10,1", "OK"},
+			{"c", "T05awatch:10;thread:1;"},
+			{"z4,10,1", "OK"},
+			{"Z1,8003,1", "OK"},
+			{"c", "T05hwbreak:;thread:1;"},
+			{"p6", "03800000"},
+			{"z1,8003,1", "OK"},
+		} {
+			var got string
+			var err error
+			if tt.cmd == "" {
+				got, err = cl.recv() // stop reply of c
+			} else {
+				got, err = cl.command(tt.cmd)
+			}
+			if err != nil {
+				return err
+			}
+			if got != tt.want {
+				return fmt.Errorf("%s: got %q, want %q", tt.cmd, got, tt.want)
+			}
+		}
+
+		// second client is refused
+		conn, err := net.Dial("tcp", srv.Addr().String())
+		if err != nil {
+			return err
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+			return fmt.Errorf("second client: %v", err)
+		}
+		conn.Close()
+
+		if err := expect("QStartNoAckMode", "OK"); err != nil {
+			return err
+		}
+		cl.noAck = true
+		for _, tt := range []struct{ cmd, want string }{
+			{"vCont?", "vCont;c;C;s;S"},
+			{"Z0,9000,1", "OK"},
+		} {
+			if err := expect(tt.cmd, tt.want); err != nil {
+				return err
+			}
+		}
+
+		// Ctrl-C pauses the core
+		if err := cl.send("vCont;c"); err != nil {
+			return err
+		}
+		time.Sleep(50 * time.Millisecond)
+		cl.conn.Write([]byte{0x03})
+		if reply, err := cl.recv(); err != nil || reply != "T02thread:1;" {
+			return fmt.Errorf("Ctrl-C: got %q %v", reply, err)
+		}
+
+		if err := expect("D", "OK"); err != nil {
+			return err
+		}
+		if _, err := cl.r.ReadByte(); err != io.EOF {
+			return fmt.Errorf("connection isn't closed after detach: %v", err)
+		}
+		return nil
+	})
+
+	if c.Paused() {
+		t.Error("core is paused after detach")
+	}
+	if bp := c.Breakpoints(); len(bp) != 0 {
+		t.Errorf("breakpoints are left after detach: %v", bp)
+	}
+}
+
+// Client disconnects without D packet
+func TestServerClose(t *testing.T) {
+	c := newTestCore(t)
+	srv, err := Listen("localhost:0", c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	runClient(t, c, srv, func(cl *client) error {
+		reply, err := cl.command("Z2,2100,40")
+		if err != nil || reply != "OK" {
+			return fmt.Errorf("Z2: %q %v", reply, err)
+		}
+		return nil
+	})
+
+	if c.Paused() || len(c.Breakpoints()) != 0 {
+		t.Errorf("paused: %v, breakpoints: %v", c.Paused(), c.Breakpoints())
+	}
+}
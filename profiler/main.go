@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/pkg/profile"
 	"github.com/pokemium/gsnes/core"
@@ -19,7 +20,9 @@ const (
 )
 
 var (
-	s = flag.Int("s", 30, "How many seconds to run the emulator.")
+	s       = flag.Int("s", 30, "How many seconds to run the emulator.")
+	format  = flag.String("format", core.FORMAT_RGBA8888, "Pixel format of frame output (RGBA8888, BGRA8888, RGB565).")
+	nocache = flag.Bool("notilecache", false, "Disable decoded tile cache (to compare rendering speed).")
 )
 
 func main() {
@@ -43,16 +46,30 @@ func run() exitCode {
 	}
 
 	c := core.New()
+	c.TileCache(!*nocache)
 	c.LoadROM(romData)
 
+	fps := 60
+	if c.Region() == core.REGION_PAL {
+		fps = 50
+	}
+
+	buf := make([]uint8, (core.HORIZONTAL*2)*(core.OVERSCAN*2)*core.PixelSize(*format))
+
 	fmt.Printf("Run emulator for %d seconds\n", *s)
-	for i := 0; i < (*s)*60; i++ {
+	start := time.Now()
+	for i := 0; i < (*s)*fps; i++ {
 		c.RunFrame()
-		c.FrameBuffer()
-		if i%60 == 0 {
-			fmt.Printf("%d sec\n", i/60+1)
+		if err := c.ReadFrame(buf, *format); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitCodeError
+		}
+		if i%fps == 0 {
+			fmt.Printf("%d sec\n", i/fps+1)
 		}
 	}
 
+	elapsed := time.Since(start)
+	fmt.Printf("%d frames in %v (%.1f fps)\n", (*s)*fps, elapsed, float64((*s)*fps)/elapsed.Seconds())
 	return ExitCodeOK
 }